@@ -0,0 +1,132 @@
+package urlresolver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// AuditRecord describes a single request to resolve a URL.
+type AuditRecord struct {
+	Time           time.Time `json:"time"`
+	GivenURL       string    `json:"given_url"`
+	ResolvedURL    string    `json:"resolved_url"`
+	Outcome        Outcome   `json:"outcome"`
+	ClientIdentity string    `json:"client_identity,omitempty"`
+	Error          string    `json:"error,omitempty"`
+}
+
+// AuditSink records every request made to a resolver, for deployments that
+// must keep track of outbound fetching.
+//
+// Records are passed to the sink synchronously before Resolve returns, so
+// sinks backed by slow storage should buffer records themselves.
+type AuditSink interface {
+	Record(ctx context.Context, record AuditRecord) error
+}
+
+// WithAuditSink makes the resolver record every request to the given sink.
+// Unlike most other observability hooks, records are made for every call to
+// Resolve, even if it was coalesced with another call for the same URL.
+//
+// Errors from the sink do not affect resolution, but are logged if the
+// resolver has a logger.
+func WithAuditSink(sink AuditSink) Option {
+	return func(r *Resolver) {
+		r.auditSink = sink
+	}
+}
+
+type clientIdentityKey struct{}
+
+// ContextWithClientIdentity returns a copy of ctx that identifies the client
+// on whose behalf URLs are resolved, for inclusion in audit records.
+func ContextWithClientIdentity(ctx context.Context, identity string) context.Context {
+	return context.WithValue(ctx, clientIdentityKey{}, identity)
+}
+
+// audit records a request to the resolver's audit sink, if any.
+func (r *Resolver) audit(ctx context.Context, givenURL string, result Result, err error) {
+	if r.auditSink == nil {
+		return
+	}
+	record := AuditRecord{
+		Time:        time.Now(),
+		GivenURL:    givenURL,
+		ResolvedURL: result.ResolvedURL,
+		Outcome:     result.Outcome,
+	}
+	record.ClientIdentity, _ = ctx.Value(clientIdentityKey{}).(string)
+	if err != nil {
+		record.Error = err.Error()
+	}
+	if err := r.auditSink.Record(ctx, record); err != nil && r.logger != nil {
+		r.logger.ErrorContext(ctx, "urlresolver: error recording audit record", "given_url", givenURL, "error", err.Error())
+	}
+}
+
+// NewWriterAuditSink returns an AuditSink that writes each record as a line
+// of JSON to the given writer (e.g. an *os.File).
+func NewWriterAuditSink(w io.Writer) AuditSink {
+	return &writerAuditSink{enc: json.NewEncoder(w)}
+}
+
+type writerAuditSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func (s *writerAuditSink) Record(_ context.Context, record AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(record)
+}
+
+// webhookAuditTimeout bounds each request made by a webhook audit sink, so
+// that a hung webhook cannot block resolution indefinitely.
+const webhookAuditTimeout = 5 * time.Second
+
+// NewWebhookAuditSink returns an AuditSink that POSTs each record as JSON to
+// the given URL using the given client. Any response other than 2xx is
+// treated as an error, as is a webhook that fails to respond within 5
+// seconds.
+func NewWebhookAuditSink(client *http.Client, webhookURL string) AuditSink {
+	return &webhookAuditSink{client: client, url: webhookURL, timeout: webhookAuditTimeout}
+}
+
+type webhookAuditSink struct {
+	client  *http.Client
+	url     string
+	timeout time.Duration
+}
+
+func (s *webhookAuditSink) Record(ctx context.Context, record AuditRecord) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	// The record should be delivered even if the resolution's context was
+	// canceled, but not at the cost of waiting forever on the webhook.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body) //nolint:errcheck
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("audit webhook error: HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package urlresolver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditSink(t *testing.T) {
+	t.Parallel()
+
+	t.Run("writer sink", func(t *testing.T) {
+		t.Parallel()
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("<title>title</title>")) //nolint:errcheck
+		}))
+		defer srv.Close()
+
+		buf := &bytes.Buffer{}
+		resolver := New(newSafeTestTransport(t), 0, WithAuditSink(NewWriterAuditSink(buf)), WithDomainBlocklist("example.com"))
+
+		ctx := ContextWithClientIdentity(context.Background(), "client-1")
+		_, err := resolver.Resolve(ctx, srv.URL+"/foo?utm_source=bar")
+		assert.NoError(t, err)
+		_, err = resolver.Resolve(context.Background(), "https://example.com/")
+		assert.Error(t, err)
+
+		var records []AuditRecord
+		dec := json.NewDecoder(buf)
+		for dec.More() {
+			var record AuditRecord
+			assert.NoError(t, dec.Decode(&record))
+			assert.False(t, record.Time.IsZero())
+			record.Time = record.Time.UTC()
+			records = append(records, record)
+		}
+		for i := range records {
+			records[i].Time = records[0].Time
+		}
+		assert.Equal(t, []AuditRecord{
+			{
+				Time:           records[0].Time,
+				GivenURL:       srv.URL + "/foo?utm_source=bar",
+				ResolvedURL:    srv.URL + "/foo",
+				Outcome:        OutcomeOK,
+				ClientIdentity: "client-1",
+			},
+			{
+				Time:        records[0].Time,
+				GivenURL:    "https://example.com/",
+				ResolvedURL: "https://example.com/",
+				Outcome:     OutcomeBlocked,
+				Error:       "blocked domain: example.com",
+			},
+		}, records)
+	})
+
+	t.Run("webhook sink", func(t *testing.T) {
+		t.Parallel()
+
+		var got AuditRecord
+		webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "POST", r.Method)
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		}))
+		defer webhook.Close()
+
+		sink := NewWebhookAuditSink(webhook.Client(), webhook.URL)
+		assert.NoError(t, sink.Record(context.Background(), AuditRecord{GivenURL: "https://example.com/", ClientIdentity: "client-1"}))
+		assert.Equal(t, "https://example.com/", got.GivenURL)
+		assert.Equal(t, "client-1", got.ClientIdentity)
+	})
+
+	t.Run("webhook sink error", func(t *testing.T) {
+		t.Parallel()
+
+		webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer webhook.Close()
+
+		sink := NewWebhookAuditSink(webhook.Client(), webhook.URL)
+		assert.Error(t, sink.Record(context.Background(), AuditRecord{}))
+	})
+
+	t.Run("webhook sink timeout", func(t *testing.T) {
+		t.Parallel()
+
+		done := make(chan struct{})
+		webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-done:
+			case <-r.Context().Done():
+			}
+		}))
+		defer webhook.Close()
+		defer close(done)
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("<title>title</title>")) //nolint:errcheck
+		}))
+		defer srv.Close()
+
+		// http.DefaultClient has no timeout of its own
+		sink := NewWebhookAuditSink(http.DefaultClient, webhook.URL)
+		sink.(*webhookAuditSink).timeout = 50 * time.Millisecond
+		resolver := New(newSafeTestTransport(t), 0, WithAuditSink(sink))
+
+		start := time.Now()
+		result, err := resolver.Resolve(context.Background(), srv.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, "title", result.Title)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("sink errors do not affect resolution", func(t *testing.T) {
+		t.Parallel()
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("<title>title</title>")) //nolint:errcheck
+		}))
+		defer srv.Close()
+
+		resolver := New(newSafeTestTransport(t), 0, WithAuditSink(failingAuditSink{}))
+		result, err := resolver.Resolve(context.Background(), srv.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, "title", result.Title)
+	})
+}
+
+type failingAuditSink struct{}
+
+func (failingAuditSink) Record(context.Context, AuditRecord) error {
+	return errors.New("sink error")
+}
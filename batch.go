@@ -0,0 +1,99 @@
+package urlresolver
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultBatchConcurrency is the number of URLs resolved at once by
+// ResolveAll, unless overridden via BatchOptions.
+const DefaultBatchConcurrency = 8
+
+// BatchOptions customizes a call to ResolveAll.
+type BatchOptions struct {
+	// Concurrency is the maximum number of URLs resolved at once. Values
+	// less than 1 mean DefaultBatchConcurrency.
+	Concurrency int
+}
+
+// BatchResult is the result of resolving one URL given to ResolveAll.
+type BatchResult struct {
+	GivenURL string
+	Result   Result
+	Err      error
+}
+
+// BatchInterface defines the interface for a URL resolver that can resolve
+// many URLs at once. It allows decorators (e.g. a caching resolver) to
+// handle a batch more efficiently than one URL at a time, such as by looking
+// up every URL in a single round trip to their cache.
+type BatchInterface interface {
+	Interface
+	ResolveAll(ctx context.Context, urls []string, opts BatchOptions) []BatchResult
+}
+
+var _ BatchInterface = &Resolver{} // Resolver implements BatchInterface
+
+// ResolveAll resolves the given URLs using the given resolver, via its own
+// ResolveAll method if it implements BatchInterface, or else by calling its
+// Resolve method for each URL as described by Resolver.ResolveAll.
+func ResolveAll(ctx context.Context, resolver Interface, urls []string, opts BatchOptions) []BatchResult {
+	if batcher, ok := resolver.(BatchInterface); ok {
+		return batcher.ResolveAll(ctx, urls, opts)
+	}
+	return resolveAll(ctx, resolver, urls, opts)
+}
+
+// ResolveAll resolves each of the given URLs, at most opts.Concurrency at a
+// time, and returns their results in the same order as the given URLs.
+//
+// Each URL is resolved as if by Resolve, so duplicate URLs are coalesced and
+// errors are reported per URL rather than failing the whole batch. If ctx is
+// done before every URL has been resolved, the remaining URLs are not
+// fetched and their results carry the context's error.
+func (r *Resolver) ResolveAll(ctx context.Context, urls []string, opts BatchOptions) []BatchResult {
+	return resolveAll(ctx, r, urls, opts)
+}
+
+// resolveAll resolves the given URLs by calling the given resolver's Resolve
+// method for each, at most opts.Concurrency at a time.
+func resolveAll(ctx context.Context, resolver Interface, urls []string, opts BatchOptions) []BatchResult {
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = DefaultBatchConcurrency
+	}
+	if concurrency > len(urls) {
+		concurrency = len(urls)
+	}
+
+	results := make([]BatchResult, len(urls))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range indexes {
+				results[idx] = resolveBatchURL(ctx, resolver, urls[idx])
+			}
+		}()
+	}
+	for idx := range urls {
+		indexes <- idx
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
+
+func resolveBatchURL(ctx context.Context, resolver Interface, givenURL string) BatchResult {
+	if err := ctx.Err(); err != nil {
+		result := Result{ResolvedURL: givenURL}
+		result.Outcome = classifyOutcome(result, err)
+		return BatchResult{GivenURL: givenURL, Result: result, Err: err}
+	}
+	result, err := resolver.Resolve(ctx, givenURL)
+	return BatchResult{GivenURL: givenURL, Result: result, Err: err}
+}
@@ -0,0 +1,170 @@
+package urlresolver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveAll(t *testing.T) {
+	t.Parallel()
+
+	t.Run("results preserve input order", func(t *testing.T) {
+		t.Parallel()
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/slow":
+				<-time.After(50 * time.Millisecond)
+				w.Header().Set("Content-Type", "text/html")
+				w.Write([]byte("<title>slow</title>")) //nolint:errcheck
+			case "/redirect":
+				http.Redirect(w, r, "/fast", http.StatusFound)
+			default:
+				w.Header().Set("Content-Type", "text/html")
+				w.Write([]byte("<title>fast</title>")) //nolint:errcheck
+			}
+		}))
+		defer srv.Close()
+
+		resolver := New(newSafeTestTransport(t), 0)
+		urls := []string{srv.URL + "/slow", srv.URL + "/redirect", "%%", srv.URL + "/fast"}
+		results := resolver.ResolveAll(context.Background(), urls, BatchOptions{Concurrency: 2})
+
+		assert.Len(t, results, len(urls))
+		for i, result := range results {
+			assert.Equal(t, urls[i], result.GivenURL)
+		}
+		assert.Equal(t, "slow", results[0].Result.Title)
+		assert.NoError(t, results[0].Err)
+		assert.Equal(t, srv.URL+"/fast", results[1].Result.ResolvedURL)
+		assert.NoError(t, results[1].Err)
+		assert.Error(t, results[2].Err)
+		assert.Equal(t, OutcomeError, results[2].Result.Outcome)
+		assert.Equal(t, "fast", results[3].Result.Title)
+		assert.NoError(t, results[3].Err)
+	})
+
+	t.Run("concurrency is bounded", func(t *testing.T) {
+		t.Parallel()
+
+		var inFlight, maxInFlight int64
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt64(&inFlight, 1)
+			defer atomic.AddInt64(&inFlight, -1)
+			for {
+				max := atomic.LoadInt64(&maxInFlight)
+				if n <= max || atomic.CompareAndSwapInt64(&maxInFlight, max, n) {
+					break
+				}
+			}
+			<-time.After(10 * time.Millisecond)
+		}))
+		defer srv.Close()
+
+		resolver := New(newSafeTestTransport(t), 0)
+		urls := make([]string, 10)
+		for i := range urls {
+			// distinct URLs, so that requests are not coalesced
+			urls[i] = srv.URL + "/" + string(rune('a'+i))
+		}
+		results := resolver.ResolveAll(context.Background(), urls, BatchOptions{Concurrency: 3})
+		for _, result := range results {
+			assert.NoError(t, result.Err)
+		}
+		assert.LessOrEqual(t, atomic.LoadInt64(&maxInFlight), int64(3))
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		resolver := New(newSafeTestTransport(t), 0)
+		results := resolver.ResolveAll(ctx, []string{"http://127.0.0.1/a", "http://127.0.0.1/b"}, BatchOptions{})
+		assert.Equal(t, []BatchResult{
+			{
+				GivenURL: "http://127.0.0.1/a",
+				Result:   Result{ResolvedURL: "http://127.0.0.1/a", Outcome: OutcomeError},
+				Err:      context.Canceled,
+			},
+			{
+				GivenURL: "http://127.0.0.1/b",
+				Result:   Result{ResolvedURL: "http://127.0.0.1/b", Outcome: OutcomeError},
+				Err:      context.Canceled,
+			},
+		}, results)
+	})
+
+	t.Run("empty batch", func(t *testing.T) {
+		t.Parallel()
+
+		resolver := New(newSafeTestTransport(t), 0)
+		assert.Empty(t, resolver.ResolveAll(context.Background(), nil, BatchOptions{}))
+	})
+}
+
+// resolverFunc adapts a function to Interface.
+type resolverFunc func(ctx context.Context, url string) (Result, error)
+
+func (f resolverFunc) Resolve(ctx context.Context, url string) (Result, error) {
+	return f(ctx, url)
+}
+
+// fakeBatchResolver implements BatchInterface, recording the batches it is
+// given.
+type fakeBatchResolver struct {
+	resolverFunc
+	batches [][]string
+}
+
+func (f *fakeBatchResolver) ResolveAll(_ context.Context, urls []string, _ BatchOptions) []BatchResult {
+	f.batches = append(f.batches, urls)
+	results := make([]BatchResult, len(urls))
+	for i, u := range urls {
+		results[i] = BatchResult{GivenURL: u, Result: Result{ResolvedURL: u, Title: "batched"}}
+	}
+	return results
+}
+
+func TestResolveAllFunc(t *testing.T) {
+	t.Parallel()
+
+	t.Run("falls back to resolving each URL", func(t *testing.T) {
+		t.Parallel()
+
+		var calls int64
+		resolver := resolverFunc(func(_ context.Context, u string) (Result, error) {
+			atomic.AddInt64(&calls, 1)
+			return Result{ResolvedURL: u, Title: "single"}, nil
+		})
+		results := ResolveAll(context.Background(), resolver, []string{"a", "b", "c"}, BatchOptions{Concurrency: 2})
+		assert.Equal(t, int64(3), atomic.LoadInt64(&calls))
+		for i, u := range []string{"a", "b", "c"} {
+			assert.Equal(t, BatchResult{GivenURL: u, Result: Result{ResolvedURL: u, Title: "single"}}, results[i])
+		}
+	})
+
+	t.Run("uses batch method when available", func(t *testing.T) {
+		t.Parallel()
+
+		resolver := &fakeBatchResolver{
+			resolverFunc: func(context.Context, string) (Result, error) {
+				t.Error("unexpected call to Resolve")
+				return Result{}, nil
+			},
+		}
+		results := ResolveAll(context.Background(), resolver, []string{"a", "b"}, BatchOptions{})
+		assert.Equal(t, [][]string{{"a", "b"}}, resolver.batches)
+		assert.Equal(t, []BatchResult{
+			{GivenURL: "a", Result: Result{ResolvedURL: "a", Title: "batched"}},
+			{GivenURL: "b", Result: Result{ResolvedURL: "b", Title: "batched"}},
+		}, results)
+	})
+}
@@ -0,0 +1,95 @@
+package urlresolver
+
+import (
+	"context"
+	"regexp"
+)
+
+// WithMaxBodySize sets the number of bytes of the final response body that
+// will be read at first while looking for its title, which defaults to
+// 500KB. Bodies may be read further, as described in WithMaxTitleSearchSize.
+//
+// Values less than 1 are ignored.
+func WithMaxBodySize(n int) Option {
+	return func(r *Resolver) {
+		if n > 0 {
+			r.maxBodySize = n
+		}
+	}
+}
+
+// WithMaxTitleSearchSize sets a hard cap on the number of bytes that will be
+// requested and read from the final response body while looking for its
+// title, which defaults to 4MB.
+//
+// If no title (or end of <head>) is found within the first WithMaxBodySize
+// bytes of a response, the resolver keeps reading in increments of that
+// size until one is found or this cap is reached.
+// This rescues large pages that front-load megabytes of inline JSON before
+// their title. Values no greater than the max body size disable it.
+func WithMaxTitleSearchSize(n int) Option {
+	return func(r *Resolver) {
+		r.maxTitleSearchSize = n
+	}
+}
+
+// titleSearchLimit returns the most we'll read of a body while looking for
+// its title, which never exceeds the resolver's max response size.
+func (r *Resolver) titleSearchLimit() int {
+	limit := max(r.maxTitleSearchSize, r.maxBodySize)
+	if r.maxResponseSize > 0 && int64(limit) > r.maxResponseSize {
+		limit = max(int(r.maxResponseSize), r.maxBodySize)
+	}
+	return limit
+}
+
+// WithHeadOnly makes the resolver only look for a title in the document's
+// <head> element, and stop reading the body as soon as the <head> ends, even
+// if no title was found. This is useful for callers who never need to scan
+// deep into a body, and avoids picking up <title> elements that belong to
+// inline SVGs in the <body>.
+func WithHeadOnly() Option {
+	return func(r *Resolver) {
+		r.headOnly = true
+	}
+}
+
+type withoutTitleKey struct{}
+
+// ContextWithoutTitle returns a copy of ctx that makes the resolver skip
+// reading the final response body for any URL resolved with it, so that no
+// title, feed, or image metadata is found. This is useful for callers that
+// only need the resolved URL.
+func ContextWithoutTitle(ctx context.Context) context.Context {
+	return context.WithValue(ctx, withoutTitleKey{}, true)
+}
+
+// titleWanted returns true unless title parsing was disabled for the given
+// request context.
+func titleWanted(ctx context.Context) bool {
+	skip, _ := ctx.Value(withoutTitleKey{}).(bool)
+	return !skip
+}
+
+// headEndRegex matches the end of a document's <head>, which may be implied
+// by the start of its <body>.
+var headEndRegex = regexp.MustCompile(`(?i)</head|<body`)
+
+// stopReadingPattern returns the pattern that, once matched, indicates that
+// enough of a body has been read. Reading the whole <head> may be required,
+// e.g. to find metadata for a detailed result.
+func (r *Resolver) stopReadingPattern(wholeHead bool) *regexp.Regexp {
+	if r.headOnly || wholeHead {
+		return headEndRegex
+	}
+	return titleFoundRegex
+}
+
+// truncateAtBody returns the part of the given document that precedes the
+// end of its <head>.
+func truncateAtBody(body []byte) []byte {
+	if loc := headEndRegex.FindIndex(body); loc != nil {
+		return body[:loc[0]]
+	}
+	return body
+}
@@ -0,0 +1,334 @@
+package urlresolver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBodyPeekOptions(t *testing.T) {
+	t.Parallel()
+
+	lateTitle := fmt.Sprintf("<html><head><script>%s</script><title>late title</title></head></html>", strings.Repeat("*", 2048))
+	svgTitle := "<html><head><meta charset=utf-8></head><body><svg><title>icon</title></svg></body></html>"
+
+	testCases := map[string]struct {
+		body      string
+		opts      []Option
+		wantRange string
+		wantTitle string
+	}{
+		"late title found by default": {
+			body:      lateTitle,
+			wantRange: fmt.Sprintf("bytes=0-%d", defaultMaxTitleSearchSize-1),
+			wantTitle: "late title",
+		},
+		"late title found beyond lower limit when range is ignored": {
+			body:      lateTitle,
+			opts:      []Option{WithMaxBodySize(1024)},
+			wantRange: fmt.Sprintf("bytes=0-%d", defaultMaxTitleSearchSize-1),
+			wantTitle: "late title",
+		},
+		"late title missed with lower search limit": {
+			body:      lateTitle,
+			opts:      []Option{WithMaxBodySize(1024), WithMaxTitleSearchSize(2048)},
+			wantRange: "bytes=0-2047",
+			wantTitle: "",
+		},
+		"late title missed when search disabled": {
+			body:      lateTitle,
+			opts:      []Option{WithMaxBodySize(1024), WithMaxTitleSearchSize(0)},
+			wantRange: "bytes=0-1023",
+			wantTitle: "",
+		},
+		"search limited by max response size": {
+			body:      lateTitle,
+			opts:      []Option{WithMaxBodySize(1024), WithMaxResponseSize(2048)},
+			wantRange: "bytes=0-2047",
+			wantTitle: "",
+		},
+		"invalid limit ignored": {
+			body:      lateTitle,
+			opts:      []Option{WithMaxBodySize(0)},
+			wantRange: fmt.Sprintf("bytes=0-%d", defaultMaxTitleSearchSize-1),
+			wantTitle: "late title",
+		},
+		"late title found in head only mode": {
+			body:      lateTitle,
+			opts:      []Option{WithHeadOnly()},
+			wantRange: fmt.Sprintf("bytes=0-%d", defaultMaxTitleSearchSize-1),
+			wantTitle: "late title",
+		},
+		"body title found by default": {
+			body:      svgTitle,
+			wantRange: fmt.Sprintf("bytes=0-%d", defaultMaxTitleSearchSize-1),
+			wantTitle: "icon",
+		},
+		"body title ignored in head only mode": {
+			body:      svgTitle,
+			opts:      []Option{WithHeadOnly()},
+			wantRange: fmt.Sprintf("bytes=0-%d", defaultMaxTitleSearchSize-1),
+			wantTitle: "",
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, tc.wantRange, r.Header.Get("Range"))
+				w.Header().Set("Content-Type", "text/html")
+				w.Write([]byte(tc.body)) //nolint:errcheck
+			})
+			resolver := New(newHandlerTestTransport(t, "example.com", handler), 0, tc.opts...)
+			result, err := resolver.Resolve(context.Background(), "https://example.com/")
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantTitle, result.Title)
+		})
+	}
+
+	t.Run("late title found beyond lower limit when range is honored", func(t *testing.T) {
+		t.Parallel()
+
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			http.ServeContent(w, r, "", time.Time{}, strings.NewReader(lateTitle))
+		})
+		resolver := New(newHandlerTestTransport(t, "example.com", handler), 0, WithMaxBodySize(1024))
+		result, err := resolver.Resolve(context.Background(), "https://example.com/")
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusPartialContent, result.StatusCode)
+		assert.Equal(t, "late title", result.Title)
+	})
+
+	t.Run("late title missed beyond search limit when range is honored", func(t *testing.T) {
+		t.Parallel()
+
+		var sent atomic.Int64
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			http.ServeContent(&countingResponseWriter{ResponseWriter: w, n: &sent}, r, "", time.Time{}, strings.NewReader(lateTitle))
+		})
+		resolver := New(newHandlerTestTransport(t, "example.com", handler), 0, WithMaxBodySize(1024), WithMaxTitleSearchSize(2048))
+		result, err := resolver.Resolve(context.Background(), "https://example.com/")
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusPartialContent, result.StatusCode)
+		assert.Equal(t, "", result.Title)
+		assert.Equal(t, int64(2048), sent.Load())
+	})
+
+	t.Run("search continues in increments while streaming", func(t *testing.T) {
+		t.Parallel()
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			mustWriteAll(t, w, `<html><head><script>{"data": "`)
+			for i := 0; i < 8; i++ {
+				mustWriteAll(t, w, strings.Repeat("*", 1024))
+				w.(http.Flusher).Flush()
+			}
+			mustWriteAll(t, w, `"}</script><title>streamed title</title>`)
+			w.(http.Flusher).Flush()
+			// never finish the response, so the resolver only succeeds if
+			// it stops reading once the title is found
+			<-r.Context().Done()
+		}))
+		defer srv.Close()
+
+		resolver := New(newSafeTestTransport(t), 0, WithMaxBodySize(1024))
+		result, err := resolver.Resolve(context.Background(), srv.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, "streamed title", result.Title)
+	})
+
+	t.Run("head only mode stops reading at end of head", func(t *testing.T) {
+		t.Parallel()
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><head><meta charset=utf-8></head>")) //nolint:errcheck
+			w.(http.Flusher).Flush()
+			// never finish the response, so the resolver only succeeds if
+			// it stops reading early
+			<-r.Context().Done()
+		}))
+		defer srv.Close()
+
+		resolver := New(newSafeTestTransport(t), 0, WithHeadOnly())
+		result, err := resolver.Resolve(context.Background(), srv.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, "", result.Title)
+	})
+	t.Run("context without title skips reading the body", func(t *testing.T) {
+		t.Parallel()
+
+		var requests int64
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt64(&requests, 1)
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><head><title>title</title>")) //nolint:errcheck
+			w.(http.Flusher).Flush()
+			// hold the response open for a while, so that concurrent
+			// requests would be coalesced if they could be
+			select {
+			case <-r.Context().Done():
+			case <-time.After(50 * time.Millisecond):
+			}
+		}))
+		defer srv.Close()
+
+		resolver := New(newSafeTestTransport(t), 0)
+
+		var wg sync.WaitGroup
+		var withTitle, withoutTitle Result
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			withTitle, _ = resolver.Resolve(context.Background(), srv.URL)
+		}()
+		go func() {
+			defer wg.Done()
+			withoutTitle, _ = resolver.Resolve(ContextWithoutTitle(context.Background()), srv.URL)
+		}()
+		wg.Wait()
+
+		assert.Equal(t, "title", withTitle.Title)
+		assert.Equal(t, "", withoutTitle.Title)
+		assert.Equal(t, srv.URL, withoutTitle.ResolvedURL)
+		assert.Equal(t, OutcomePartialNoTitle, withoutTitle.Outcome)
+		assert.False(t, withoutTitle.Coalesced)
+		assert.Equal(t, int64(2), atomic.LoadInt64(&requests))
+	})
+}
+
+func TestLargeBodies(t *testing.T) {
+	t.Parallel()
+
+	// A huge inline script without any other tags, which must be searched
+	// in bounded time per read.
+	script := strings.Repeat("*", 3*1024*1024)
+	lateTitle := "<html><head><script>" + script + "</script><title>late title</title></head></html>"
+
+	t.Run("servers that honor range", func(t *testing.T) {
+		t.Parallel()
+
+		var sent atomic.Int64
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			http.ServeContent(&countingResponseWriter{ResponseWriter: w, n: &sent}, r, "", time.Time{}, strings.NewReader(lateTitle))
+		})
+		resolver := New(newHandlerTestTransport(t, "example.com", handler), 0)
+		result, err := resolver.Resolve(context.Background(), "https://example.com/")
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusPartialContent, result.StatusCode)
+		assert.Equal(t, "late title", result.Title)
+		assert.LessOrEqual(t, sent.Load(), int64(defaultMaxTitleSearchSize))
+	})
+
+	t.Run("buffers holding a full max body size read are reused", func(t *testing.T) {
+		t.Parallel()
+
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><head><script>" + script)) //nolint:errcheck
+		})
+		resolver := New(newHandlerTestTransport(t, "example.com", handler), 0, WithMaxTitleSearchSize(0))
+		_, err := resolver.Resolve(context.Background(), "https://example.com/")
+		assert.NoError(t, err)
+
+		stats := resolver.BufferPoolStats()
+		assert.Equal(t, uint64(1), stats.Puts)
+		assert.Equal(t, uint64(0), stats.Discarded)
+	})
+
+	t.Run("servers that ignore range", func(t *testing.T) {
+		t.Parallel()
+
+		testCases := map[string]struct {
+			body      string
+			wantTitle string
+		}{
+			"late title found": {
+				body:      lateTitle,
+				wantTitle: "late title",
+			},
+			"no title within search limit": {
+				body:      "<html><head><script>" + script + script,
+				wantTitle: "",
+			},
+		}
+		for name, tc := range testCases {
+			tc := tc
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+
+				handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Content-Type", "text/html")
+					w.Write([]byte(tc.body)) //nolint:errcheck
+				})
+				resolver := New(newHandlerTestTransport(t, "example.com", handler), 0)
+				result, err := resolver.Resolve(context.Background(), "https://example.com/")
+				assert.NoError(t, err)
+				assert.Equal(t, http.StatusOK, result.StatusCode)
+				assert.Equal(t, tc.wantTitle, result.Title)
+			})
+		}
+	})
+}
+
+// countingResponseWriter counts the bytes written to a response body.
+type countingResponseWriter struct {
+	http.ResponseWriter
+	n *atomic.Int64
+}
+
+func (w *countingResponseWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.n.Add(int64(n))
+	return n, err
+}
+
+func BenchmarkReadHeadWithoutTags(b *testing.B) {
+	body := []byte("<html><head><script>" + strings.Repeat("*", defaultMaxTitleSearchSize))
+	buf := &bytes.Buffer{}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		readHead(buf, bytes.NewReader(body), len(body), titleFoundRegex) //nolint:errcheck
+	}
+}
+
+func TestCoalesceKey(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	base := coalesceKey(ctx, "https://example.com/")
+	assert.Equal(t, "https://example.com/", base)
+
+	keys := map[string]bool{base: true}
+	for _, ctx := range []context.Context{
+		ContextWithoutTitle(ctx),
+		ContextWithMaxRedirects(ctx, 0),
+		ContextWithMaxRedirects(ctx, 1),
+		ContextWithMaxRedirects(ContextWithoutTitle(ctx), 1),
+	} {
+		key := coalesceKey(ctx, "https://example.com/")
+		assert.False(t, keys[key], "duplicate key %q", key)
+		keys[key] = true
+	}
+	assert.Equal(t,
+		coalesceKey(ContextWithoutTitle(ContextWithMaxRedirects(ctx, 1)), "https://example.com/"),
+		coalesceKey(ContextWithMaxRedirects(ContextWithoutTitle(ctx), 1), "https://example.com/"),
+	)
+}
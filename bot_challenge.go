@@ -0,0 +1,22 @@
+package urlresolver
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ErrBotChallenge is returned when the final response is a bot detection
+// challenge rather than the content that was requested. The result still
+// holds the resolved URL.
+var ErrBotChallenge = errors.New("bot challenge")
+
+// cloudflareChallengeFingerprint is recorded in Result.Interstitial when we
+// receive a Cloudflare bot challenge.
+const cloudflareChallengeFingerprint = "cloudflare_challenge"
+
+// isBotChallenge returns true if the given response is a bot detection
+// challenge, which Cloudflare marks with a "Cf-Mitigated: challenge" header.
+func isBotChallenge(resp *http.Response) bool {
+	return strings.EqualFold(resp.Header.Get("Cf-Mitigated"), "challenge")
+}
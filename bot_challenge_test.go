@@ -0,0 +1,36 @@
+package urlresolver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBotChallenge(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/challenge", http.StatusFound)
+			return
+		}
+		w.Header().Set("Cf-Mitigated", "challenge")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("<title>Just a moment...</title>")) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	resolver := New(newSafeTestTransport(t), 0)
+	result, err := resolver.Resolve(context.Background(), srv.URL+"/redirect")
+	assert.ErrorIs(t, err, ErrBotChallenge)
+	assert.Equal(t, Result{
+		ResolvedURL:      srv.URL + "/challenge",
+		IntermediateURLs: []string{srv.URL + "/redirect"},
+		Outcome:          OutcomeBotChallenge,
+		StatusCode:       403,
+		Interstitial:     "cloudflare_challenge",
+	}, withoutHops(t, result))
+}
@@ -3,32 +3,91 @@ package bufferpool
 import (
 	"bytes"
 	"sync"
+	"sync/atomic"
 )
 
+// DefaultMaxCapacity is the largest buffer capacity retained by a BufferPool,
+// unless overridden via WithMaxCapacity.
+const DefaultMaxCapacity = 64 * 1024
+
 // BufferPool uses a sync.Pool to manage a pool of bytes.Buffer.
 type BufferPool struct {
-	pool *sync.Pool
+	pool        *sync.Pool
+	maxCapacity int
+
+	gets      atomic.Uint64
+	allocs    atomic.Uint64
+	puts      atomic.Uint64
+	discarded atomic.Uint64
+}
+
+// Option customizes a BufferPool.
+type Option func(*BufferPool)
+
+// WithMaxCapacity sets the largest buffer capacity retained by the pool.
+// Buffers that have grown beyond it are dropped rather than returned to the
+// pool, so that a few unusually large uses don't permanently inflate the
+// memory held by the pool. A value less than 1 disables the limit.
+func WithMaxCapacity(n int) Option {
+	return func(bp *BufferPool) {
+		bp.maxCapacity = n
+	}
 }
 
 // New creates a new BufferPool.
-func New() *BufferPool {
-	return &BufferPool{
-		pool: &sync.Pool{
-			New: func() interface{} {
-				return new(bytes.Buffer)
-			},
+func New(opts ...Option) *BufferPool {
+	bp := &BufferPool{maxCapacity: DefaultMaxCapacity}
+	bp.pool = &sync.Pool{
+		New: func() interface{} {
+			bp.allocs.Add(1)
+			return new(bytes.Buffer)
 		},
 	}
+	for _, opt := range opts {
+		opt(bp)
+	}
+	return bp
 }
 
 // Get gets a bytes.Buffer from the pool.
 func (bp *BufferPool) Get() *bytes.Buffer {
+	bp.gets.Add(1)
 	buf := bp.pool.Get().(*bytes.Buffer)
 	buf.Reset()
 	return buf
 }
 
-// Put puts a bytes.Buffer back into the pool.
+// Put puts a bytes.Buffer back into the pool, unless it has grown beyond the
+// pool's maximum capacity.
 func (bp *BufferPool) Put(buf *bytes.Buffer) {
+	if bp.maxCapacity > 0 && buf.Cap() > bp.maxCapacity {
+		bp.discarded.Add(1)
+		return
+	}
+	bp.puts.Add(1)
 	bp.pool.Put(buf)
 }
+
+// Stats reports on the usage of a BufferPool.
+type Stats struct {
+	// Gets is the number of buffers taken from the pool.
+	Gets uint64
+	// Allocs is the number of new buffers allocated because the pool was
+	// empty.
+	Allocs uint64
+	// Puts is the number of buffers returned to the pool.
+	Puts uint64
+	// Discarded is the number of buffers dropped instead of being returned
+	// to the pool because they exceeded its maximum capacity.
+	Discarded uint64
+}
+
+// Stats returns the pool's usage statistics.
+func (bp *BufferPool) Stats() Stats {
+	return Stats{
+		Gets:      bp.gets.Load(),
+		Allocs:    bp.allocs.Load(),
+		Puts:      bp.puts.Load(),
+		Discarded: bp.discarded.Load(),
+	}
+}
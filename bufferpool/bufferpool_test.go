@@ -15,3 +15,45 @@ func TestBufferPool(t *testing.T) {
 	assert.NoError(t, err)
 	p.Put(b)
 }
+
+func TestMaxCapacity(t *testing.T) {
+	testCases := map[string]struct {
+		opts          []Option
+		size          int
+		wantDiscarded uint64
+	}{
+		"small buffer retained": {
+			size:          1024,
+			wantDiscarded: 0,
+		},
+		"oversized buffer discarded": {
+			size:          DefaultMaxCapacity + 1,
+			wantDiscarded: 1,
+		},
+		"custom max capacity": {
+			opts:          []Option{WithMaxCapacity(512)},
+			size:          1024,
+			wantDiscarded: 1,
+		},
+		"limit disabled": {
+			opts:          []Option{WithMaxCapacity(0)},
+			size:          DefaultMaxCapacity * 2,
+			wantDiscarded: 0,
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			p := New(tc.opts...)
+			b := p.Get()
+			b.Grow(tc.size)
+			p.Put(b)
+
+			stats := p.Stats()
+			assert.Equal(t, uint64(1), stats.Gets)
+			assert.Equal(t, uint64(1), stats.Allocs)
+			assert.Equal(t, tc.wantDiscarded, stats.Discarded)
+			assert.Equal(t, 1-tc.wantDiscarded, stats.Puts)
+		})
+	}
+}
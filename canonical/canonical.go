@@ -0,0 +1,374 @@
+package canonical
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/idna"
+
+	"github.com/mccutchen/urlresolver/internal/urlutil"
+)
+
+// NormalizationFlags defines the normalizations applied during
+// canonicalization.
+var NormalizationFlags = (FlagsSafe |
+	FlagRemoveDotSegments |
+	FlagRemoveDuplicateSlashes |
+	FlagDecodeDWORDHost |
+	FlagDecodeOctalHost |
+	FlagDecodeHexHost |
+	FlagRemoveUnnecessaryHostDots |
+	FlagRemoveEmptyPortSeparator)
+
+// defaultParamRules are the built-in query param rules, which may be replaced
+// via WithParamRules.
+var defaultParamRules = ParamRules{
+	// Query parameters matching these patterns will ALWAYS be stripped.  The
+	// categorized patterns below were largely sourced from this Chrome
+	// Extension:
+	//
+	// https://github.com/newhouse/url-tracking-stripper/blob/dea6c144/README.md#documentation
+	{
+		ExcludeParams: []string{
+			// Google's Urchin Tracking Module & Google Adwords
+			`utm_.+`,
+			`gclid`,
+
+			// Adobe Omniture SiteCatalyst
+			`icid`,
+
+			// Facebook
+			`fbclid`,
+
+			// Hubspot
+			`_hsenc`,
+			`_hsmi`,
+
+			// Marketo
+			`mkt_.+`,
+
+			// MailChimp
+			`mc_.+`,
+
+			// Simple Reach
+			`sr_.+`,
+
+			// Vero
+			`vero_.+`,
+
+			// Unknown
+			`nr_email_referer`,
+			`ncid`,
+			`ref`,
+
+			// Other ad trackers?
+			`ad(set)?_(name|id)`,
+			`omega_(ad|adset|utm)_.+`,
+			`campaign_id`,
+			`variant`,
+
+			// Miscellaneous garbage-looking params noticed by @mccutchen while
+			// perusing logs
+			`_r`,
+			`cmpid`,
+			`currentPage`,
+			`fsrc`,
+			`mb?id`,
+			`mobile_touch`,
+			`ocid`,
+			`rss`,
+			`s_(sub)?src`,
+			`smid`,
+			`wpsrc`,
+		},
+	},
+
+	// Per-domain lists of allowed query parameters
+	{
+		Domains:     []string{"youtube.com"},
+		AllowParams: []string{`v`, `p`, `t`, `list`},
+	},
+	{
+		// really, this should be restricted to twitter.com/search?q=, but
+		// allowing q= on any twitter URL is probably okay
+		Domains:     []string{"twitter.com"},
+		AllowParams: []string{`q`},
+	},
+
+	// All query params will be stripped from these domains, which tend to be
+	// content-focused web sites.
+	//
+	// TODO: this could potentially make us miss roll some urls up together
+	// (e.g. in the case of /search?q=foo on a domain), but I think it"s worth
+	// it for now.
+	{
+		Domains: []string{
+			"bbc.co.uk",
+			"buzzfeed.com",
+			"deadspin.com",
+			"economist.com",
+			"grantland.com",
+			"huffingtonpost.com",
+			"instagram.com",
+			"newyorker.com",
+			"nymag.com",
+			"nytimes.com",
+			"slate.com",
+			"techcrunch.com",
+			"theguardian.com",
+			"theonion.com",
+			"twitter.com",
+			"vanityfair.com",
+			"vulture.com",
+			"washingtonpost.com",
+			"wsj.com",
+		},
+		StripAllParams: true,
+	},
+}
+
+var (
+	// Paths under these domains will be lowercased, as they tend to be
+	// usernames that are treated as case-insensitive but may appear in a
+	// variety of cases (e.g. twitter.com/McCutchen and twitter.com/mccutchen
+	// are equivalent).
+	defaultLowercaseDomains = []string{
+		"instagram.com",
+		"twitter.com",
+	}
+
+	// Default documents removed by WithDirectoryIndexRemoval
+	directoryIndexPattern = regexp.MustCompile(`(?i)(^|/)(default|index)\.(html?|php|aspx?|jsp)$`)
+)
+
+// Canonicalize filters unnecessary query params and then normalizes a URL,
+// ensuring consistent case, encoding, sorting of params, etc, according to
+// the package-level defaults.
+func Canonicalize(u *url.URL) string {
+	// Building the default canonicalizer on every call picks up any changes
+	// to NormalizationFlags, and costs no allocation since it doesn't escape.
+	return defaultCanonicalizer().Canonicalize(u)
+}
+
+// DisplayURL converts the hostname of a canonicalized URL from its ASCII
+// (punycode) form back to Unicode, for display to users. Hosts that may be
+// homographs of other hosts are better displayed in ASCII; see
+// Result.SuspiciousHost.
+func DisplayURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	host, err := idna.Display.ToUnicode(u.Hostname())
+	if err != nil || host == u.Hostname() {
+		return rawURL
+	}
+	// url.URL.String would percent-encode the Unicode hostname, so we
+	// replace it in the authority directly
+	idx := strings.Index(rawURL, "//")
+	if idx < 0 {
+		return rawURL
+	}
+	return rawURL[:idx] + strings.Replace(rawURL[idx:], u.Hostname(), host, 1)
+}
+
+// Canonicalizer canonicalizes URLs according to a configurable set of rules.
+// The zero value is not usable; use New instead.
+type Canonicalizer struct {
+	flags             NormalizationFlag
+	paramRules        paramRuleSet
+	lowercaseDomains  []string
+	preserveFragments bool
+	fragmentDomains   []string
+	routeFragments    bool
+	trimSlashes       bool
+	trimIndex         bool
+	domainPlugins     []domainPlugin
+	trackingMode      TrackingParamMode
+	trackingReporter  TrackingParamReporter
+	sessionParams     *regexp.Regexp
+
+	// err records an invalid option, to be returned by New
+	err error
+}
+
+// Option customizes a Canonicalizer.
+type Option func(*Canonicalizer)
+
+// New creates a new Canonicalizer, which starts from the current
+// package-level defaults (NormalizationFlags and DefaultParamRules) before
+// applying the given options.
+func New(opts ...Option) (*Canonicalizer, error) {
+	c := defaultCanonicalizer()
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	return c, nil
+}
+
+// WithParamRules replaces the rules used to strip query params.
+func WithParamRules(rules ParamRules) Option {
+	return func(c *Canonicalizer) {
+		c.paramRules, c.err = compileParamRules(rules)
+	}
+}
+
+// WithNormalizationFlags replaces the normalizations applied to URLs.
+func WithNormalizationFlags(flags NormalizationFlag) Option {
+	return func(c *Canonicalizer) {
+		c.flags = flags
+	}
+}
+
+// WithLowercaseDomains replaces the list of domains whose paths are
+// lowercased.
+func WithLowercaseDomains(domains ...string) Option {
+	return func(c *Canonicalizer) {
+		c.lowercaseDomains = urlutil.NormalizeDomains(domains)
+	}
+}
+
+// WithPreserveFragments controls whether fragment identifiers are kept on all
+// URLs. By default, fragments are removed.
+func WithPreserveFragments(preserve bool) Option {
+	return func(c *Canonicalizer) {
+		c.preserveFragments = preserve
+	}
+}
+
+// WithPreserveFragmentDomains keeps fragment identifiers on URLs under the
+// given domains (or any of their subdomains), where they tend to point at
+// specific content that users care about (e.g. line anchors on github.com).
+func WithPreserveFragmentDomains(domains ...string) Option {
+	return func(c *Canonicalizer) {
+		c.fragmentDomains = urlutil.NormalizeDomains(domains)
+	}
+}
+
+// WithPreserveRouteFragments controls whether fragment identifiers that look
+// like single-page app routes (e.g. #!/foo or #/foo) are kept on all URLs.
+// Disabled by default.
+func WithPreserveRouteFragments(enabled bool) Option {
+	return func(c *Canonicalizer) {
+		c.routeFragments = enabled
+	}
+}
+
+// WithDefaultPortRemoval controls whether default ports (:80 for http, :443
+// for https) are removed. Enabled by default.
+func WithDefaultPortRemoval(enabled bool) Option {
+	return func(c *Canonicalizer) {
+		if enabled {
+			c.flags |= FlagRemoveDefaultPort
+		} else {
+			c.flags &^= FlagRemoveDefaultPort
+		}
+	}
+}
+
+// WithTrailingSlashRemoval controls whether trailing slashes are removed from
+// non-root paths, so that e.g. /foo/ and /foo are equivalent. Disabled by
+// default.
+func WithTrailingSlashRemoval(enabled bool) Option {
+	return func(c *Canonicalizer) {
+		c.trimSlashes = enabled
+	}
+}
+
+// WithDirectoryIndexRemoval controls whether default documents like
+// index.html are removed from paths, so that e.g. /foo/index.html and /foo/
+// are equivalent. Disabled by default.
+func WithDirectoryIndexRemoval(enabled bool) Option {
+	return func(c *Canonicalizer) {
+		c.trimIndex = enabled
+	}
+}
+
+// defaultCanonicalizer returns a Canonicalizer configured by the current
+// package-level defaults.
+func defaultCanonicalizer() *Canonicalizer {
+	return &Canonicalizer{
+		flags:            NormalizationFlags,
+		paramRules:       compiledDefaultParamRules,
+		lowercaseDomains: defaultLowercaseDomains,
+		sessionParams:    defaultSessionParamPattern,
+	}
+}
+
+// Canonicalize filters unnecessary query params and then normalizes a URL,
+// ensuring consistent case, encoding, sorting of params, etc.
+func (c *Canonicalizer) Canonicalize(u *url.URL) string {
+	return c.normalize(c.applyDomainPlugins(c.clean(u)))
+}
+
+// normalize normalizes a URL, ensuring consistent case, encoding, sorting of
+// params, etc. Internationalized hostnames are converted to their ASCII
+// (punycode) form, so that e.g. münchen.de and xn--mnchen-3ya.de are
+// equivalent.
+func (c *Canonicalizer) normalize(u *url.URL) string {
+	u.Host = urlutil.ASCIIAuthority(u.Host)
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if urlutil.MatchDomains(host, c.lowercaseDomains) {
+		u.Path = strings.ToLower(u.Path)
+	}
+	if c.trimIndex {
+		u.Path = directoryIndexPattern.ReplaceAllString(u.Path, "$1")
+	}
+	if c.trimSlashes {
+		if trimmed := strings.TrimRight(u.Path, "/"); trimmed != "" {
+			u.Path = trimmed
+		}
+	}
+	return normalizeURL(u, c.flags)
+}
+
+// clean removes unnecessary query params, session identifiers, and fragment
+// identifiers from a URL.
+func (c *Canonicalizer) clean(u *url.URL) *url.URL {
+	u.RawQuery = c.filterParams(u).Encode()
+	if path := c.stripSessionPathParams(u.Path); path != u.Path {
+		u.Path, u.RawPath = path, ""
+	}
+	if !c.shouldPreserveFragment(u) {
+		u.Fragment = ""
+		u.RawFragment = ""
+	}
+	return u
+}
+
+func (c *Canonicalizer) shouldPreserveFragment(u *url.URL) bool {
+	if u.Fragment == "" {
+		return false
+	}
+	if c.preserveFragments {
+		return true
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if urlutil.MatchDomains(host, c.fragmentDomains) {
+		return true
+	}
+	// Single-page apps that route on the fragment, including the old
+	// "hashbang" convention
+	return c.routeFragments && (strings.HasPrefix(u.Fragment, "!") || strings.HasPrefix(u.Fragment, "/"))
+}
+
+func (c *Canonicalizer) filterParams(u *url.URL) url.Values {
+	rules := c.paramRules.forURL(u)
+	filtered := url.Values{}
+	for param, values := range u.Query() {
+		if rules.shouldExclude(param) || c.isSessionParam(param) {
+			continue
+		}
+		if c.isProbableTrackingParam(u, rules, param, values) {
+			continue
+		}
+		for _, v := range values {
+			filtered.Add(param, v)
+		}
+	}
+	return filtered
+}
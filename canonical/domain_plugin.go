@@ -0,0 +1,163 @@
+package canonical
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/mccutchen/urlresolver/internal/urlutil"
+)
+
+// DomainPlugin rewrites a URL on a particular domain into its canonical form,
+// e.g. by reducing a product page URL to just its product ID. Plugins are
+// applied after query params have been filtered but before normalization,
+// and should modify the given URL in place.
+type DomainPlugin func(u *url.URL)
+
+type domainPlugin struct {
+	domains []string
+	plugin  DomainPlugin
+}
+
+// WithDomainPlugin registers a DomainPlugin for the given domains and their
+// subdomains. Plugins are applied in the order they are registered.
+func WithDomainPlugin(plugin DomainPlugin, domains ...string) Option {
+	return func(c *Canonicalizer) {
+		c.domainPlugins = append(c.domainPlugins, domainPlugin{urlutil.NormalizeDomains(domains), plugin})
+	}
+}
+
+// WithBuiltinDomainPlugins registers the built-in DomainPlugins, which reduce
+// Amazon and eBay product URLs to their IDs (AmazonProductPlugin and
+// EbayItemPlugin) and map well-known mobile hosts to their desktop
+// equivalents (MobileHostPlugin).
+func WithBuiltinDomainPlugins() Option {
+	return func(c *Canonicalizer) {
+		c.domainPlugins = append(c.domainPlugins, builtinDomainPlugins...)
+	}
+}
+
+// WithoutDomainPlugins removes all previously registered DomainPlugins.
+func WithoutDomainPlugins() Option {
+	return func(c *Canonicalizer) {
+		c.domainPlugins = nil
+	}
+}
+
+// applyDomainPlugins applies each DomainPlugin registered for the given
+// URL's domain.
+func (c *Canonicalizer) applyDomainPlugins(u *url.URL) *url.URL {
+	if len(c.domainPlugins) == 0 {
+		return u
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	for _, p := range c.domainPlugins {
+		if urlutil.MatchDomains(host, p.domains) {
+			p.plugin(u)
+		}
+	}
+	return u
+}
+
+var builtinDomainPlugins = []domainPlugin{
+	{
+		domains: []string{
+			"amazon.ca",
+			"amazon.co.jp",
+			"amazon.co.uk",
+			"amazon.com",
+			"amazon.com.au",
+			"amazon.com.br",
+			"amazon.com.mx",
+			"amazon.de",
+			"amazon.es",
+			"amazon.fr",
+			"amazon.in",
+			"amazon.it",
+			"amazon.nl",
+		},
+		plugin: AmazonProductPlugin,
+	},
+	{
+		domains: []string{
+			"ebay.ca",
+			"ebay.co.uk",
+			"ebay.com",
+			"ebay.com.au",
+			"ebay.de",
+			"ebay.fr",
+			"ebay.it",
+		},
+		plugin: EbayItemPlugin,
+	},
+	{
+		domains: []string{
+			"facebook.com",
+			"twitter.com",
+			"wikipedia.org",
+			"wiktionary.org",
+			"youtube.com",
+		},
+		plugin: MobileHostPlugin,
+	},
+}
+
+var (
+	amazonProductPattern = regexp.MustCompile(`^(?:/[^/]+)?/(?:dp|gp/product|gp/aw/d)/([A-Z0-9]{10})(?:[/?]|$)`)
+	ebayItemPattern      = regexp.MustCompile(`^/itm/(?:[^/]+/)?(\d+)(?:/|$)`)
+)
+
+// AmazonProductPlugin is a DomainPlugin that reduces Amazon product URLs,
+// which often include a product name slug and various tracking params, to
+// /dp/{ASIN}.
+func AmazonProductPlugin(u *url.URL) {
+	if matches := amazonProductPattern.FindStringSubmatch(u.Path); matches != nil {
+		setPath(u, "/dp/"+matches[1])
+	}
+}
+
+// EbayItemPlugin is a DomainPlugin that reduces eBay item URLs, which may
+// include an item name slug and various tracking params, to /itm/{ID}.
+func EbayItemPlugin(u *url.URL) {
+	if matches := ebayItemPattern.FindStringSubmatch(u.Path); matches != nil {
+		setPath(u, "/itm/"+matches[1])
+	}
+}
+
+// setPath replaces the given URL's path and removes its query params.
+func setPath(u *url.URL, path string) {
+	u.Path = path
+	u.RawPath = ""
+	u.RawQuery = ""
+}
+
+// mobileHosts maps mobile hostnames to their desktop equivalents.
+var mobileHosts = map[string]string{
+	"m.facebook.com":      "www.facebook.com",
+	"mobile.facebook.com": "www.facebook.com",
+	"m.twitter.com":       "twitter.com",
+	"mobile.twitter.com":  "twitter.com",
+	"m.youtube.com":       "www.youtube.com",
+}
+
+// MobileHostPlugin is a DomainPlugin that maps well-known mobile hosts to
+// their desktop equivalents (e.g. m.youtube.com to www.youtube.com, or
+// en.m.wikipedia.org to en.wikipedia.org), so that links shared from phones
+// and desktops are equivalent.
+func MobileHostPlugin(u *url.URL) {
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	desktopHost, ok := mobileHosts[host]
+	if !ok {
+		// Wikimedia sites put the mobile label after the language, e.g.
+		// en.m.wikipedia.org
+		labels := strings.Split(host, ".")
+		if len(labels) != 4 || labels[1] != "m" {
+			return
+		}
+		desktopHost = labels[0] + "." + strings.Join(labels[2:], ".")
+	}
+	if port := u.Port(); port != "" {
+		desktopHost += ":" + port
+	}
+	u.Host = desktopHost
+}
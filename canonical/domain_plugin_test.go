@@ -0,0 +1,66 @@
+package canonical
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestDomainPlugins(t *testing.T) {
+	t.Parallel()
+
+	upperPlugin := func(u *url.URL) {
+		u.Path = strings.ToUpper(u.Path)
+	}
+	custom, err := New(WithBuiltinDomainPlugins(), WithDomainPlugin(upperPlugin, "example.com"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	none, err := New(WithBuiltinDomainPlugins(), WithoutDomainPlugins())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	testCases := []struct {
+		testCase
+		canonicalizer *Canonicalizer
+	}{
+		{testCase{"amazon product with slug", "https://www.amazon.com/Some-Product-Name/dp/B07FZ8S74R/ref=sr_1_3?keywords=foo&qid=123", "https://www.amazon.com/dp/B07FZ8S74R"}, custom},
+		{testCase{"amazon gp product", "https://www.amazon.co.uk/gp/product/B07FZ8S74R?psc=1", "https://www.amazon.co.uk/dp/B07FZ8S74R"}, custom},
+		{testCase{"amazon non-product", "https://www.amazon.com/gp/help/customer?nodeId=123", "https://www.amazon.com/gp/help/customer?nodeId=123"}, custom},
+		{testCase{"ebay item with slug", "https://www.ebay.com/itm/Some-Item-Name/123456789012?hash=item1&var=2", "https://www.ebay.com/itm/123456789012"}, custom},
+		{testCase{"ebay item", "https://www.ebay.de/itm/123456789012?_trkparms=foo", "https://www.ebay.de/itm/123456789012"}, custom},
+		{testCase{"mobile youtube", "https://m.youtube.com/watch?v=abcd1234&feature=share", "https://www.youtube.com/watch?v=abcd1234"}, custom},
+		{testCase{"mobile twitter", "https://mobile.twitter.com/McCutchen/status/12345", "https://twitter.com/mccutchen/status/12345"}, custom},
+		{testCase{"mobile wikipedia", "https://en.m.wikipedia.org/wiki/Go_(programming_language)", "https://en.wikipedia.org/wiki/Go_(programming_language)"}, custom},
+		{testCase{"mobile facebook with port", "https://m.facebook.com:8443/foo", "https://www.facebook.com:8443/foo"}, custom},
+		{testCase{"desktop wikipedia", "https://en.wikipedia.org/wiki/Go", "https://en.wikipedia.org/wiki/Go"}, custom},
+		{testCase{"other mobile hosts", "https://m.example.org/foo", "https://m.example.org/foo"}, custom},
+		{testCase{"custom plugin", "https://www.example.com/foo?bar=baz", "https://www.example.com/FOO?bar=baz"}, custom},
+		{testCase{"custom plugin other domains", "https://example.org/foo", "https://example.org/foo"}, custom},
+		{testCase{"plugins removed", "https://www.amazon.com/Some-Product-Name/dp/B07FZ8S74R", "https://www.amazon.com/Some-Product-Name/dp/B07FZ8S74R"}, none},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			u, err := url.Parse(tc.given)
+			if err != nil {
+				t.Errorf("error parsing %s: %s", tc.given, err)
+			}
+
+			result := tc.canonicalizer.Canonicalize(u)
+			if result != tc.expected {
+				t.Errorf("\nGot:  %s\nWant: %s", result, tc.expected)
+			}
+		})
+	}
+
+	t.Run("built-in plugins not used by default", func(t *testing.T) {
+		t.Parallel()
+		u, _ := url.Parse("https://m.youtube.com/watch?v=abcd1234")
+		if result, want := Canonicalize(u), "https://m.youtube.com/watch?v=abcd1234"; result != want {
+			t.Errorf("\nGot:  %s\nWant: %s", result, want)
+		}
+	})
+}
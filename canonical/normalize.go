@@ -0,0 +1,398 @@
+package canonical
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// NormalizationFlag is a set of normalizations applied to URLs during
+// canonicalization, which may be combined with bitwise OR.
+type NormalizationFlag uint
+
+// Normalizations that may be applied during canonicalization.
+const (
+	// HTTP://host/ -> http://host/
+	FlagLowercaseScheme NormalizationFlag = 1 << iota
+	// http://HOST/ -> http://host/
+	FlagLowercaseHost
+	// http://host:80/ -> http://host/ and https://host:443/ -> https://host/
+	FlagRemoveDefaultPort
+	// http://host/a/./b/../c -> http://host/a/c
+	FlagRemoveDotSegments
+	// http://host/a//b -> http://host/a/b
+	FlagRemoveDuplicateSlashes
+	// http://1113982867/ -> http://66.102.7.147/
+	FlagDecodeDWORDHost
+	// http://0102.0146.07.0223/ -> http://66.102.7.147/
+	FlagDecodeOctalHost
+	// http://0x42660793/ -> http://66.102.7.147/
+	FlagDecodeHexHost
+	// http://.host../ -> http://host/
+	FlagRemoveUnnecessaryHostDots
+	// http://host:/ -> http://host/
+	FlagRemoveEmptyPortSeparator
+	// http://host/path/ -> http://host/path
+	FlagRemoveTrailingSlash
+
+	// FlagsSafe are normalizations that never change the meaning of a URL.
+	// Percent-encoding is always normalized, regardless of flags.
+	FlagsSafe = FlagLowercaseScheme | FlagLowercaseHost | FlagRemoveDefaultPort
+)
+
+// normalizeURL applies the given normalizations to u, modifying it in place,
+// and returns its consistently escaped string form.
+//
+// The normalizations and escaping match those of the
+// github.com/PuerkitoBio/purell package, which this replaces.
+func normalizeURL(u *url.URL, flags NormalizationFlag) string {
+	if flags&FlagLowercaseScheme != 0 {
+		u.Scheme = strings.ToLower(u.Scheme)
+	}
+	if flags&FlagLowercaseHost != 0 {
+		u.Host = strings.ToLower(u.Host)
+	}
+	if flags&FlagRemoveDefaultPort != 0 {
+		u.Host = removeDefaultPort(u.Scheme, u.Host)
+	}
+	if flags&FlagRemoveDotSegments != 0 {
+		u.Path = removeDotSegments(u.Path, u.Host)
+	}
+	if flags&FlagRemoveDuplicateSlashes != 0 {
+		u.Path = removeDuplicateSlashes(u.Path)
+	}
+	if flags&FlagDecodeDWORDHost != 0 {
+		u.Host = decodeDWORDHost(u.Host)
+	}
+	if flags&FlagDecodeOctalHost != 0 {
+		u.Host = decodeOctalHost(u.Host)
+	}
+	if flags&FlagDecodeHexHost != 0 {
+		u.Host = decodeHexHost(u.Host)
+	}
+	if flags&FlagRemoveUnnecessaryHostDots != 0 {
+		u.Host = removeUnnecessaryHostDots(u.Host)
+	}
+	if flags&FlagRemoveEmptyPortSeparator != 0 {
+		u.Host = strings.TrimRight(u.Host, ":")
+	}
+	if flags&FlagRemoveTrailingSlash != 0 {
+		if u.Path != "" {
+			u.Path = strings.TrimSuffix(u.Path, "/")
+		} else {
+			u.Host = strings.TrimSuffix(u.Host, "/")
+		}
+	}
+	return escapeURL(u)
+}
+
+// removeDefaultPort removes the port from host if it is the default port for
+// the given scheme.
+func removeDefaultPort(scheme, host string) string {
+	switch strings.ToLower(scheme) {
+	case "http":
+		return strings.TrimSuffix(host, ":80")
+	case "https":
+		return strings.TrimSuffix(host, ":443")
+	}
+	return host
+}
+
+// removeDotSegments resolves "." and ".." segments in path.
+func removeDotSegments(path, host string) string {
+	if path == "" || !hasDotSegment(path) {
+		return path
+	}
+
+	var (
+		segments  = strings.Split(path, "/")
+		dotFree   = segments[:0]
+		lastIsDot bool
+	)
+	for _, s := range segments {
+		switch s {
+		case "..":
+			if len(dotFree) > 0 {
+				dotFree = dotFree[:len(dotFree)-1]
+			}
+		case ".":
+		default:
+			dotFree = append(dotFree, s)
+		}
+		lastIsDot = s == "." || s == ".."
+	}
+
+	path = strings.Join(dotFree, "/")
+	if host != "" && !strings.HasSuffix(host, "/") && !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	if lastIsDot && !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+	return path
+}
+
+// hasDotSegment returns true if any segment of path is "." or "..".
+func hasDotSegment(path string) bool {
+	for len(path) > 0 {
+		seg := path
+		if i := strings.IndexByte(path, '/'); i >= 0 {
+			seg, path = path[:i], path[i+1:]
+		} else {
+			path = ""
+		}
+		if seg == "." || seg == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// removeDuplicateSlashes collapses runs of slashes in path.
+func removeDuplicateSlashes(path string) string {
+	if !strings.Contains(path, "//") {
+		return path
+	}
+	var b strings.Builder
+	b.Grow(len(path))
+	for i := 0; i < len(path); i++ {
+		if path[i] == '/' && i > 0 && path[i-1] == '/' {
+			continue
+		}
+		b.WriteByte(path[i])
+	}
+	return b.String()
+}
+
+// splitNumericHost splits a host like "1113982867.:8080" into its leading
+// part and its suffix of optional trailing dots and an optional port, which
+// may be empty. ok is false if the suffix is malformed.
+func splitNumericHost(host string) (number, suffix string, ok bool) {
+	end := len(host)
+	if i := strings.LastIndexByte(host, ':'); i >= 0 {
+		if !isDigits(host[i+1:], true) {
+			return "", "", false
+		}
+		end = i
+	}
+	for end > 0 && host[end-1] == '.' {
+		end--
+	}
+	return host[:end], host[end:], true
+}
+
+// decodeDWORDHost decodes a host given as a single decimal number.
+func decodeDWORDHost(host string) string {
+	number, suffix, ok := splitNumericHost(host)
+	if !ok || !isDigits(number, false) {
+		return host
+	}
+	dword, _ := strconv.ParseInt(number, 10, 0)
+	return formatIPv4([4]int64{dword >> 24 & 0xFF, dword >> 16 & 0xFF, dword >> 8 & 0xFF, dword & 0xFF}, suffix)
+}
+
+// decodeOctalHost decodes a host given as four dotted octal numbers.
+func decodeOctalHost(host string) string {
+	number, suffix, ok := splitNumericHost(host)
+	if !ok {
+		return host
+	}
+	var parts [4]int64
+	for i := range parts {
+		part := number
+		if i < 3 {
+			j := strings.IndexByte(number, '.')
+			if j < 0 {
+				return host
+			}
+			part, number = number[:j], number[j+1:]
+		}
+		if !strings.HasPrefix(part, "0") || !isDigits(part, false) {
+			return host
+		}
+		// Invalid or out of range parts are not rejected, for
+		// compatibility with purell
+		parts[i], _ = strconv.ParseInt(part, 8, 0)
+	}
+	return formatIPv4(parts, suffix)
+}
+
+// decodeHexHost decodes a host given as a single hexadecimal number.
+func decodeHexHost(host string) string {
+	number, suffix, ok := splitNumericHost(host)
+	if !ok || !strings.HasPrefix(number, "0x") || !isHexDigits(number[2:]) {
+		return host
+	}
+	dword, _ := strconv.ParseInt(number[2:], 16, 0)
+	return decodeDWORDHost(strconv.FormatInt(dword, 10) + suffix)
+}
+
+// formatIPv4 formats the given parts as a dotted IPv4 address, followed by
+// the given suffix.
+func formatIPv4(parts [4]int64, suffix string) string {
+	buf := make([]byte, 0, len("255.255.255.255")+len(suffix))
+	for i, part := range parts {
+		if i > 0 {
+			buf = append(buf, '.')
+		}
+		buf = strconv.AppendInt(buf, part, 10)
+	}
+	return string(append(buf, suffix...))
+}
+
+// removeUnnecessaryHostDots removes leading, trailing, and repeated dots from
+// host.
+func removeUnnecessaryHostDots(host string) string {
+	if host == "" || !strings.Contains(host, ".") {
+		return host
+	}
+	name, port := host, ""
+	if i := strings.LastIndexByte(host, ':'); i > 0 && isDigits(host[i+1:], false) {
+		name, port = host[:i], host[i:]
+	}
+	trimmed := strings.Trim(name, ".")
+	if !strings.Contains(trimmed, "..") {
+		if len(trimmed) == len(name) {
+			return host
+		}
+		return trimmed + port
+	}
+	name = trimmed
+	var b strings.Builder
+	b.Grow(len(name) + len(port))
+	for i := 0; i < len(name); i++ {
+		if name[i] == '.' && i > 0 && name[i-1] == '.' {
+			continue
+		}
+		b.WriteByte(name[i])
+	}
+	b.WriteString(port)
+	return b.String()
+}
+
+// isDigits returns true if s consists only of decimal digits, and is not
+// empty unless allowEmpty is true.
+func isDigits(s string, allowEmpty bool) bool {
+	if s == "" {
+		return allowEmpty
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// isHexDigits returns true if s is a non-empty string of hexadecimal digits.
+func isHexDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if unhex(s[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func unhex(c byte) int {
+	switch {
+	case '0' <= c && c <= '9':
+		return int(c - '0')
+	case 'a' <= c && c <= 'f':
+		return int(c - 'a' + 10)
+	case 'A' <= c && c <= 'F':
+		return int(c - 'A' + 10)
+	}
+	return -1
+}
+
+type escapeMode int
+
+const (
+	escapePath escapeMode = iota
+	escapeFragment
+)
+
+// shouldEscape returns true if c must be escaped in the given part of a URL,
+// according to RFC 3986. Unlike net/url, reserved characters that are allowed
+// in paths are left alone.
+func shouldEscape(c byte, mode escapeMode) bool {
+	if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' {
+		return false
+	}
+	switch c {
+	case '-', '.', '_', '~':
+		return false
+	case ':', '/', '?', '#', '[', ']', '@', '!', '$', '&', '\'', '(', ')', '*', '+', ',', ';', '=':
+		if mode == escapePath {
+			return c == '?' || c == '#'
+		}
+		return c == '#'
+	}
+	return true
+}
+
+// writeEscaped writes s to b, percent-encoding any characters that must be
+// escaped in the given part of a URL.
+func writeEscaped(b *strings.Builder, s string, mode escapeMode) {
+	const upperhex = "0123456789ABCDEF"
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; shouldEscape(c, mode) {
+			b.WriteByte('%')
+			b.WriteByte(upperhex[c>>4])
+			b.WriteByte(upperhex[c&15])
+		} else {
+			b.WriteByte(c)
+		}
+	}
+}
+
+// userinfoReplacer unescapes characters that net/url escapes in userinfo but
+// RFC 3986 does not require to be escaped.
+var userinfoReplacer = strings.NewReplacer(
+	"%21", "!",
+	"%27", "'",
+	"%28", "(",
+	"%29", ")",
+	"%2A", "*",
+)
+
+// escapeURL assembles u into a string, like url.URL.String, but escaping
+// only the characters that RFC 3986 requires, so that equivalent URLs
+// produce identical strings. Any RawPath or RawFragment is ignored.
+func escapeURL(u *url.URL) string {
+	var b strings.Builder
+	b.Grow(len(u.Scheme) + len(u.Opaque) + len(u.Host) + len(u.Path) + len(u.RawQuery) + len(u.Fragment) + 16)
+	if u.Scheme != "" {
+		b.WriteString(u.Scheme)
+		b.WriteByte(':')
+	}
+	if u.Opaque != "" {
+		b.WriteString(u.Opaque)
+	} else {
+		if u.Scheme != "" || u.Host != "" || u.User != nil {
+			b.WriteString("//")
+			if u.User != nil {
+				b.WriteString(userinfoReplacer.Replace(u.User.String()))
+				b.WriteByte('@')
+			}
+			b.WriteString(u.Host)
+		}
+		if u.Path != "" && u.Path[0] != '/' && u.Host != "" {
+			b.WriteByte('/')
+		}
+		writeEscaped(&b, u.Path, escapePath)
+	}
+	if u.RawQuery != "" {
+		b.WriteByte('?')
+		b.WriteString(u.RawQuery)
+	}
+	if u.Fragment != "" {
+		b.WriteByte('#')
+		writeEscaped(&b, u.Fragment, escapeFragment)
+	}
+	return b.String()
+}
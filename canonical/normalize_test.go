@@ -0,0 +1,79 @@
+package canonical
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"testing"
+)
+
+// normalizationFlagSets are the sets of flags for which testdata/normalize.json
+// records the expected output of each URL.
+var normalizationFlagSets = map[string]NormalizationFlag{
+	"default":                NormalizationFlags,
+	"default_keep_port":      NormalizationFlags &^ FlagRemoveDefaultPort,
+	"default_trailing_slash": NormalizationFlags | FlagRemoveTrailingSlash,
+	"safe":                   FlagsSafe,
+	"none":                   0,
+}
+
+type normalizeTestCase struct {
+	Given    string            `json:"given"`
+	Expected map[string]string `json:"expected"`
+}
+
+// loadNormalizeTestCases loads the output of github.com/PuerkitoBio/purell,
+// which our normalizer replaced, for a variety of URLs and flags.
+func loadNormalizeTestCases(t testing.TB) []normalizeTestCase {
+	data, err := os.ReadFile("testdata/normalize.json")
+	if err != nil {
+		t.Fatalf("error reading test cases: %s", err)
+	}
+	var testCases []normalizeTestCase
+	if err := json.Unmarshal(data, &testCases); err != nil {
+		t.Fatalf("error parsing test cases: %s", err)
+	}
+	return testCases
+}
+
+// TestNormalizeURLMatchesPurell ensures that normalizeURL matches the behavior
+// of the purell package for the flags we support.
+func TestNormalizeURLMatchesPurell(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range loadNormalizeTestCases(t) {
+		for name, flags := range normalizationFlagSets {
+			want, ok := tc.Expected[name]
+			if !ok {
+				t.Fatalf("missing expected result for %q with %s flags", tc.Given, name)
+			}
+			u, err := url.Parse(tc.Given)
+			if err != nil {
+				t.Fatalf("invalid test URL %q: %s", tc.Given, err)
+			}
+			if got := normalizeURL(u, flags); got != want {
+				t.Errorf("%s flags, given %q\nGot:  %s\nWant: %s", name, tc.Given, got, want)
+			}
+		}
+	}
+}
+
+func BenchmarkNormalizeURL(b *testing.B) {
+	for _, given := range []string{
+		"https://example.com/foo/bar?a=1&b=2",
+		"HTTP://WWW.Example.COM:80/a/./b/../c//d%7e?q=1#frag",
+	} {
+		given := given
+		parsed, err := url.Parse(given)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(given, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				u := *parsed
+				normalizeURL(&u, NormalizationFlags)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package canonical
+
+import (
+	"regexp"
+	"regexp/syntax"
+	"strings"
+
+	"github.com/mccutchen/urlresolver/internal/urlutil"
+)
+
+// maxExpandedParams limits the number of exact names or prefixes a single
+// param pattern may be expanded into, beyond which the pattern is matched
+// with a regexp instead.
+const maxExpandedParams = 64
+
+// paramMatcher matches param names against a list of patterns, which are
+// implicitly anchored at both ends, optionally ignoring case.
+//
+// Canonicalization checks every param of every hop against these patterns,
+// so the common simple cases are precompiled into exact names (e.g. fbclid
+// or ad(set)?_(name|id)) and prefixes (e.g. utm_.+), and only the remaining
+// patterns are matched with a regexp.
+type paramMatcher struct {
+	foldCase bool
+	exact    map[string]struct{}
+	prefixes []paramPrefix
+
+	// rest matches the patterns that could not be precompiled, if any, and
+	// all matches everything, for params that can't take the fast path.
+	rest *regexp.Regexp
+	all  *regexp.Regexp
+}
+
+// paramPrefix matches any param starting with prefix, optionally followed by
+// at least one more character.
+type paramPrefix struct {
+	prefix   string
+	nonEmpty bool
+}
+
+// compileParamMatcher compiles the given patterns, which are matched
+// case-insensitively if foldCase is true, or returns nil if there are none.
+func compileParamMatcher(patterns []string, foldCase bool) (*paramMatcher, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	prefix := `^(`
+	if foldCase {
+		prefix = `(?i)^(`
+	}
+	all, err := compileOptionalPattern(prefix, `)$`, patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &paramMatcher{foldCase: foldCase, exact: make(map[string]struct{}), all: all}
+	var rest []string
+	for _, pattern := range patterns {
+		if !m.precompile(pattern) {
+			rest = append(rest, pattern)
+		}
+	}
+	if m.rest, err = compileOptionalPattern(prefix, `)$`, rest); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// precompile adds the given pattern to the matcher's exact names or
+// prefixes, if possible.
+func (m *paramMatcher) precompile(pattern string) bool {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return false
+	}
+	re = re.Simplify()
+
+	// A pattern like utm_.+ matches a set of prefixes
+	if re.Op == syntax.OpConcat && len(re.Sub) > 1 {
+		last := re.Sub[len(re.Sub)-1]
+		if (last.Op == syntax.OpPlus || last.Op == syntax.OpStar) && last.Sub[0].Op == syntax.OpAnyCharNotNL {
+			head := &syntax.Regexp{Op: syntax.OpConcat, Sub: re.Sub[:len(re.Sub)-1]}
+			prefixes, ok := expandParams(head, m.foldCase)
+			if !ok {
+				return false
+			}
+			for _, prefix := range prefixes {
+				m.prefixes = append(m.prefixes, paramPrefix{prefix: prefix, nonEmpty: last.Op == syntax.OpPlus})
+			}
+			return true
+		}
+	}
+
+	names, ok := expandParams(re, m.foldCase)
+	if !ok {
+		return false
+	}
+	for _, name := range names {
+		m.exact[name] = struct{}{}
+	}
+	return true
+}
+
+// expandParams returns the strings matched by re, lowercased if foldCase is
+// true, if it matches a small, finite set of ASCII strings.
+func expandParams(re *syntax.Regexp, foldCase bool) ([]string, bool) {
+	switch re.Op {
+	case syntax.OpEmptyMatch:
+		return []string{""}, true
+	case syntax.OpLiteral:
+		// A case-insensitive literal within a case-sensitive pattern, e.g.
+		// (?i)foo, is left to the regexp
+		if !foldCase && re.Flags&syntax.FoldCase != 0 {
+			return nil, false
+		}
+		for _, r := range re.Rune {
+			if r >= 0x80 {
+				return nil, false
+			}
+		}
+		return []string{foldParam(string(re.Rune), foldCase)}, true
+	case syntax.OpCapture:
+		return expandParams(re.Sub[0], foldCase)
+	case syntax.OpQuest:
+		sub, ok := expandParams(re.Sub[0], foldCase)
+		if !ok {
+			return nil, false
+		}
+		return append(sub, ""), true
+	case syntax.OpAlternate:
+		var all []string
+		for _, sub := range re.Sub {
+			strs, ok := expandParams(sub, foldCase)
+			if !ok || len(all)+len(strs) > maxExpandedParams {
+				return nil, false
+			}
+			all = append(all, strs...)
+		}
+		return all, true
+	case syntax.OpConcat:
+		all := []string{""}
+		for _, sub := range re.Sub {
+			strs, ok := expandParams(sub, foldCase)
+			if !ok || len(all)*len(strs) > maxExpandedParams {
+				return nil, false
+			}
+			product := make([]string, 0, len(all)*len(strs))
+			for _, a := range all {
+				for _, b := range strs {
+					product = append(product, a+b)
+				}
+			}
+			all = product
+		}
+		return all, true
+	case syntax.OpCharClass:
+		var all []string
+		for i := 0; i < len(re.Rune); i += 2 {
+			lo, hi := re.Rune[i], re.Rune[i+1]
+			if hi >= 0x80 || len(all)+int(hi-lo)+1 > maxExpandedParams {
+				return nil, false
+			}
+			for r := lo; r <= hi; r++ {
+				all = append(all, foldParam(string(r), foldCase))
+			}
+		}
+		return all, true
+	}
+	return nil, false
+}
+
+// match returns true if the given param matches any of the patterns.
+func (m *paramMatcher) match(param string) bool {
+	if !urlutil.IsASCII(param) {
+		// Case folding of non-ASCII names is left to the regexp package
+		return m.all.MatchString(param)
+	}
+	param = foldParam(param, m.foldCase)
+	if _, ok := m.exact[param]; ok {
+		return true
+	}
+	for _, p := range m.prefixes {
+		if strings.HasPrefix(param, p.prefix) {
+			rest := param[len(p.prefix):]
+			if (!p.nonEmpty || rest != "") && !strings.Contains(rest, "\n") {
+				return true
+			}
+		}
+	}
+	return m.rest != nil && m.rest.MatchString(param)
+}
+
+// foldParam lowercases the given ASCII param name if foldCase is true.
+func foldParam(param string, foldCase bool) string {
+	if foldCase {
+		return strings.ToLower(param)
+	}
+	return param
+}
@@ -0,0 +1,251 @@
+package canonical
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mccutchen/urlresolver/internal/urlutil"
+)
+
+// ParamRule describes query params to be stripped from URLs during
+// canonicalization. A rule with no Domains or URLPattern applies to every
+// URL.
+//
+// Param patterns are regular expressions that must match the entire param
+// name. ExcludeParams are matched case-insensitively and AllowParams are
+// case-sensitive, while URLPattern and Exceptions are case-insensitive, as
+// in the ClearURLs format.
+type ParamRule struct {
+	// Domains restricts the rule to URLs on these domains or their
+	// subdomains.
+	Domains []string `json:"domains,omitempty"`
+
+	// URLPattern restricts the rule to URLs matching this regular
+	// expression.
+	URLPattern string `json:"url_pattern,omitempty"`
+
+	// Exceptions are regular expressions matching URLs the rule does not
+	// apply to.
+	Exceptions []string `json:"exceptions,omitempty"`
+
+	// ExcludeParams are always stripped.
+	ExcludeParams []string `json:"exclude_params,omitempty"`
+
+	// AllowParams, if given, are the only params kept.
+	AllowParams []string `json:"allow_params,omitempty"`
+
+	// StripAllParams strips every param not kept by the AllowParams of an
+	// applicable rule.
+	StripAllParams bool `json:"strip_all_params,omitempty"`
+}
+
+// ParamRules is an ordered list of ParamRules, all of which are applied to
+// every URL they match.
+type ParamRules []ParamRule
+
+// DefaultParamRules returns the built-in rules used during canonicalization,
+// which may be extended and passed to WithParamRules.
+func DefaultParamRules() ParamRules {
+	rules := make(ParamRules, len(defaultParamRules))
+	copy(rules, defaultParamRules)
+	return rules
+}
+
+// LoadParamRules reads ParamRules from a JSON array of rules.
+func LoadParamRules(r io.Reader) (ParamRules, error) {
+	var rules ParamRules
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&rules); err != nil {
+		return nil, fmt.Errorf("invalid param rules: %w", err)
+	}
+	if _, err := compileParamRules(rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// LoadClearURLsRules reads ParamRules from a ClearURLs rules database (see
+// https://docs.clearurls.xyz/latest/specs/rules/).
+//
+// Each provider's rules and referral marketing params are stripped from
+// URLs matching its urlPattern, except for its exceptions. Raw rules and
+// redirections are not supported, and providers whose patterns are not
+// valid Go regular expressions are skipped.
+func LoadClearURLsRules(r io.Reader) (ParamRules, error) {
+	var db struct {
+		Providers map[string]struct {
+			URLPattern        string   `json:"urlPattern"`
+			Rules             []string `json:"rules"`
+			ReferralMarketing []string `json:"referralMarketing"`
+			Exceptions        []string `json:"exceptions"`
+		} `json:"providers"`
+	}
+	if err := json.NewDecoder(r).Decode(&db); err != nil {
+		return nil, fmt.Errorf("invalid clearurls rules: %w", err)
+	}
+
+	names := make([]string, 0, len(db.Providers))
+	for name := range db.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rules := make(ParamRules, 0, len(names))
+	for _, name := range names {
+		provider := db.Providers[name]
+		rule := ParamRule{
+			URLPattern:    provider.URLPattern,
+			Exceptions:    provider.Exceptions,
+			ExcludeParams: append(provider.Rules, provider.ReferralMarketing...),
+		}
+		if len(rule.ExcludeParams) == 0 {
+			continue
+		}
+		if _, err := compileParamRule(rule); err != nil {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// compiledDefaultParamRules are the compiled form of defaultParamRules, used
+// by Canonicalize and as the starting point for New.
+var compiledDefaultParamRules = mustCompileParamRules(defaultParamRules)
+
+func mustCompileParamRules(rules ParamRules) paramRuleSet {
+	compiled, err := compileParamRules(rules)
+	if err != nil {
+		panic(err)
+	}
+	return compiled
+}
+
+type paramRuleSet []*paramRule
+
+type paramRule struct {
+	domains    []string
+	urlPattern *regexp.Regexp
+	exceptions *regexp.Regexp
+	exclude    *paramMatcher
+	allow      *paramMatcher
+	stripAll   bool
+}
+
+func compileParamRules(rules ParamRules) (paramRuleSet, error) {
+	compiled := make(paramRuleSet, 0, len(rules))
+	for i, rule := range rules {
+		c, err := compileParamRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid param rule %d: %w", i, err)
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled, nil
+}
+
+func compileParamRule(rule ParamRule) (*paramRule, error) {
+	var err error
+	c := &paramRule{
+		domains:  urlutil.NormalizeDomains(rule.Domains),
+		stripAll: rule.StripAllParams,
+	}
+	if rule.URLPattern != "" {
+		if c.urlPattern, err = regexp.Compile(`(?i)` + rule.URLPattern); err != nil {
+			return nil, err
+		}
+	}
+	if c.exceptions, err = compileOptionalPattern(`(?i)(`, `)`, rule.Exceptions); err != nil {
+		return nil, err
+	}
+	if c.exclude, err = compileParamMatcher(rule.ExcludeParams, true); err != nil {
+		return nil, err
+	}
+	if c.allow, err = compileParamMatcher(rule.AllowParams, false); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// compileOptionalPattern combines the given patterns into a single regexp,
+// or returns nil if there are none. Each pattern is grouped on its own, so
+// that flags like (?i) in one pattern don't leak into the next.
+func compileOptionalPattern(prefix string, suffix string, patterns []string) (*regexp.Regexp, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	return regexp.Compile(prefix + "(?:" + strings.Join(patterns, ")|(?:") + ")" + suffix)
+}
+
+// forURL returns the rules that apply to the given URL.
+func (rules paramRuleSet) forURL(u *url.URL) paramRuleSet {
+	var (
+		host    = strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+		rawURL  = u.String()
+		matched paramRuleSet
+	)
+	for _, rule := range rules {
+		if len(rule.domains) > 0 && !urlutil.MatchDomains(host, rule.domains) {
+			continue
+		}
+		if rule.urlPattern != nil && !rule.urlPattern.MatchString(rawURL) {
+			continue
+		}
+		if rule.exceptions != nil && rule.exceptions.MatchString(rawURL) {
+			continue
+		}
+		matched = append(matched, rule)
+	}
+	return matched
+}
+
+// shouldExclude returns true if the given param should be stripped according
+// to the rules, which must already be filtered to the URL being cleaned.
+func (rules paramRuleSet) shouldExclude(param string) bool {
+	// Is this a param we always strip?
+	for _, rule := range rules {
+		if rule.exclude != nil && rule.exclude.match(param) {
+			return true
+		}
+	}
+
+	// Is there a param allowlist, and is this param on it?
+	allowlisted := false
+	for _, rule := range rules {
+		if rule.allow != nil {
+			if rule.allow.match(param) {
+				return false
+			}
+			allowlisted = true
+		}
+	}
+	if allowlisted {
+		return true
+	}
+
+	// Finally, do we strip all params?  If not, default to allowing the
+	// param.
+	for _, rule := range rules {
+		if rule.stripAll {
+			return true
+		}
+	}
+	return false
+}
+
+// allows returns true if the given param is explicitly allowed by the rules,
+// which must already be filtered to the URL being cleaned.
+func (rules paramRuleSet) allows(param string) bool {
+	for _, rule := range rules {
+		if rule.allow != nil && rule.allow.match(param) {
+			return true
+		}
+	}
+	return false
+}
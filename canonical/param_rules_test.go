@@ -0,0 +1,207 @@
+package canonical
+
+import (
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadParamRules(t *testing.T) {
+	t.Parallel()
+
+	t.Run("valid rules", func(t *testing.T) {
+		t.Parallel()
+		rules, err := LoadParamRules(strings.NewReader(`[
+			{"exclude_params": ["foo_.+"]},
+			{"domains": ["example.com"], "allow_params": ["id"], "strip_all_params": true}
+		]`))
+		assert.NoError(t, err)
+		assert.Equal(t, ParamRules{
+			{ExcludeParams: []string{"foo_.+"}},
+			{Domains: []string{"example.com"}, AllowParams: []string{"id"}, StripAllParams: true},
+		}, rules)
+	})
+
+	testCases := map[string]string{
+		"invalid json":    `{`,
+		"unknown field":   `[{"exclude": ["foo"]}]`,
+		"invalid pattern": `[{"exclude_params": ["foo("]}]`,
+	}
+	for name, given := range testCases {
+		given := given
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			_, err := LoadParamRules(strings.NewReader(given))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoadClearURLsRules(t *testing.T) {
+	t.Parallel()
+
+	f, err := os.Open("testdata/clearurls.json")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	rules, err := LoadClearURLsRules(f)
+	assert.NoError(t, err)
+	assert.Equal(t, ParamRules{
+		{
+			URLPattern:    `^https?:\/\/(?:[a-z0-9-]+\.)*?amazon(?:\.[a-z]{2,}){1,}`,
+			Exceptions:    []string{},
+			ExcludeParams: []string{"pd_rd_[a-z]*", "qid", "sr", "tag"},
+		},
+		{
+			URLPattern:    ".*",
+			Exceptions:    []string{`^https?:\/\/[^/]+\/login`},
+			ExcludeParams: []string{"itm_[a-z_]*", "pk_campaign", "ref_?"},
+		},
+	}, rules)
+
+	_, err = LoadClearURLsRules(strings.NewReader(`{`))
+	assert.Error(t, err)
+}
+
+func TestWithParamRules(t *testing.T) {
+	t.Parallel()
+
+	f, err := os.Open("testdata/clearurls.json")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	clearURLsRules, err := LoadClearURLsRules(f)
+	assert.NoError(t, err)
+
+	c, err := New(WithParamRules(append(DefaultParamRules(), clearURLsRules...)))
+	assert.NoError(t, err)
+
+	testCases := []testCase{
+		{
+			name:     "provider rules applied to matching urls",
+			given:    "https://www.amazon.co.uk/dp/B000?pd_rd_w=1&qid=2&tag=3&th=1",
+			expected: "https://www.amazon.co.uk/dp/B000?th=1",
+		},
+		{
+			name:     "provider rules not applied to other urls",
+			given:    "https://example.com/?qid=2&tag=3",
+			expected: "https://example.com/?qid=2&tag=3",
+		},
+		{
+			name:     "global rules applied",
+			given:    "https://example.com/?itm_source=1&pk_campaign=2&ref_=3&id=4",
+			expected: "https://example.com/?id=4",
+		},
+		{
+			name:     "global rules exceptions",
+			given:    "https://example.com/login?itm_source=1",
+			expected: "https://example.com/login?itm_source=1",
+		},
+		{
+			name:     "default rules still applied",
+			given:    "https://www.youtube.com/watch?v=abcd1234&itm_source=1&utm_source=2&foo=bar",
+			expected: "https://www.youtube.com/watch?v=abcd1234",
+		},
+		{
+			name:     "provider rules are case-insensitive",
+			given:    "https://WWW.Amazon.com/dp/B000?QID=2&Tag=3&th=1",
+			expected: "https://www.amazon.com/dp/B000?th=1",
+		},
+		{
+			name:     "allowed params are case-sensitive",
+			given:    "https://www.youtube.com/watch?V=abcd1234&v=efgh5678",
+			expected: "https://www.youtube.com/watch?v=efgh5678",
+		},
+	}
+	for _, tc := range testCases {
+		u, err := url.Parse(tc.given)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expected, c.Canonicalize(u), tc.name)
+	}
+
+	u, _ := url.Parse("https://example.com/?itm_source=1")
+	assert.Equal(t, "https://example.com/?itm_source=1", Canonicalize(u), "package defaults are unaffected")
+
+	_, err = New(WithParamRules(ParamRules{{URLPattern: "("}}))
+	assert.Error(t, err)
+}
+
+// TestParamMatcher ensures that precompiled param matchers agree with the
+// regexps they replace.
+func TestParamMatcher(t *testing.T) {
+	t.Parallel()
+
+	patterns := [][]string{
+		DefaultParamRules()[0].ExcludeParams,
+		{`v`, `p`, `t`, `list`},
+		{`utm_.*`, `x[0-9]`, `(a|b)(c|d)?e`, `[^a]z`, `foo(bar)+`, `(?s)s_.+`, `é.+`, `K`},
+		{`(?i)list`, `[A-Z]`, `Cur(rent)?Page`},
+		{``},
+	}
+	params := []string{
+		"", "utm_", "utm_source", "UTM_Medium", "utm", "xutm_source", "utm_\n",
+		"gclid", "GCLID", "gclidx", "ad_name", "adset_id", "ads_id", "adset_",
+		"mid", "mbid", "mbbid", "s_src", "s_subsrc", "S_SubSrc", "omega_utm_x",
+		"omega_ad_", "currentpage", "CurrentPage", "ref", "reference", "v", "V",
+		"list", "lists", "x1", "X9", "xa", "ace", "bde", "ae", "cde", "zz", "az",
+		"foobar", "foobarbar", "foo", "s_\nx", "é1", "É1", "k", "\u212a", "ſ",
+		"LIST", "Q", "CurPage", "curpage",
+	}
+
+	for _, foldCase := range []bool{true, false} {
+		prefix := `^(`
+		if foldCase {
+			prefix = `(?i)^(`
+		}
+		for _, ps := range patterns {
+			m, err := compileParamMatcher(ps, foldCase)
+			if err != nil {
+				t.Fatalf("error compiling %q: %s", ps, err)
+			}
+			want := regexp.MustCompile(prefix + `(?:` + strings.Join(ps, `)|(?:`) + `))$`)
+			for _, param := range params {
+				if got, want := m.match(param), want.MatchString(param); got != want {
+					t.Errorf("patterns %q, foldCase %v, param %q\nGot:  %v\nWant: %v", ps, foldCase, param, got, want)
+				}
+			}
+		}
+	}
+
+	// every default pattern takes the fast path
+	m, err := compileParamMatcher(DefaultParamRules()[0].ExcludeParams, true)
+	assert.NoError(t, err)
+	assert.Nil(t, m.rest)
+}
+
+func BenchmarkShouldExclude(b *testing.B) {
+	rules, err := compileParamRules(DefaultParamRules())
+	if err != nil {
+		b.Fatal(err)
+	}
+	u, _ := url.Parse("https://example.com/foo")
+	matched := rules.forURL(u)
+	params := []string{"utm_source", "id", "page", "fbclid", "adset_name", "omega_utm_campaign", "currentPage", "q"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, param := range params {
+			matched.shouldExclude(param)
+		}
+	}
+}
+
+func BenchmarkCanonicalize(b *testing.B) {
+	given, _ := url.Parse("https://www.example.com/a/b?utm_source=x&utm_medium=y&id=1&page=2&fbclid=abc&ref=foo")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		u := *given
+		Canonicalize(&u)
+	}
+}
@@ -0,0 +1,61 @@
+package canonical
+
+import (
+	"regexp"
+)
+
+// defaultSessionParams are well-known session identifiers that are stripped
+// from query params and path parameters (e.g. /foo;jsessionid=123). Generic
+// names like sid are left out, since sites also use them for other things;
+// they may be stripped by listing them with WithSessionParams.
+var defaultSessionParams = []string{
+	`aspsessionid[a-z]*`,
+	`jsessionid`,
+	`phpsessid`,
+}
+
+var defaultSessionParamPattern = mustCompileSessionParams(defaultSessionParams)
+
+// WithSessionParams replaces the patterns matching session identifiers,
+// which are stripped from query params and path parameters. Patterns are
+// case-insensitive regular expressions that must match the entire param
+// name. Passing no patterns disables session identifier stripping.
+func WithSessionParams(patterns ...string) Option {
+	return func(c *Canonicalizer) {
+		c.sessionParams, c.err = compileSessionParams(patterns)
+	}
+}
+
+func compileSessionParams(patterns []string) (*regexp.Regexp, error) {
+	return compileOptionalPattern(`(?i)^(`, `)$`, patterns)
+}
+
+func mustCompileSessionParams(patterns []string) *regexp.Regexp {
+	re, err := compileSessionParams(patterns)
+	if err != nil {
+		panic(err)
+	}
+	return re
+}
+
+// pathParamPattern matches a single path parameter, e.g. ;jsessionid=123
+var pathParamPattern = regexp.MustCompile(`;([^/;=]+)=[^/;]*`)
+
+// isSessionParam returns true if the given param is a session identifier.
+func (c *Canonicalizer) isSessionParam(param string) bool {
+	return c.sessionParams != nil && c.sessionParams.MatchString(param)
+}
+
+// stripSessionPathParams removes session identifiers embedded in the given
+// path as path parameters.
+func (c *Canonicalizer) stripSessionPathParams(path string) string {
+	if c.sessionParams == nil {
+		return path
+	}
+	return pathParamPattern.ReplaceAllStringFunc(path, func(param string) string {
+		if c.isSessionParam(pathParamPattern.FindStringSubmatch(param)[1]) {
+			return ""
+		}
+		return param
+	})
+}
@@ -0,0 +1,106 @@
+package canonical
+
+import (
+	"math"
+	"net/url"
+	"regexp"
+)
+
+// TrackingParamMode controls the heuristic detection of tracking params
+// that are not covered by the static ParamRules.
+type TrackingParamMode int
+
+// Possible tracking param modes.
+const (
+	// TrackingParamsIgnored disables heuristic detection.
+	TrackingParamsIgnored TrackingParamMode = iota
+
+	// TrackingParamsReported reports probable tracking params without
+	// stripping them, to help build confidence in the heuristics.
+	TrackingParamsReported
+
+	// TrackingParamsStripped reports and strips probable tracking params.
+	TrackingParamsStripped
+)
+
+// TrackingParamReporter is called with each query param that looks like a
+// tracking param, along with the URL it was found on.
+type TrackingParamReporter func(u *url.URL, param string)
+
+// WithTrackingParamHeuristics enables heuristic detection of tracking
+// params, which look for long, high-entropy values like the base64 or hex
+// tokens that many click trackers generate uniquely for each click. Params
+// explicitly allowed by the ParamRules are never considered.
+//
+// The given reporter, if not nil, is called for each probable tracking
+// param.
+func WithTrackingParamHeuristics(mode TrackingParamMode, reporter TrackingParamReporter) Option {
+	return func(c *Canonicalizer) {
+		c.trackingMode = mode
+		c.trackingReporter = reporter
+	}
+}
+
+const (
+	// minTrackingValueLength is the shortest value considered a probable
+	// tracking token.
+	minTrackingValueLength = 16
+
+	// minTrackingValueEntropy is the lowest Shannon entropy, in bits per
+	// character, of a value considered a probable tracking token. Random hex
+	// and base64 strings of minTrackingValueLength sit comfortably above
+	// this, while most words and identifiers do not.
+	minTrackingValueEntropy = 3.5
+)
+
+var (
+	tokenValuePattern = regexp.MustCompile(`^[A-Za-z0-9_\-+/=.]+$`)
+	letterPattern     = regexp.MustCompile(`[A-Za-z]`)
+	digitPattern      = regexp.MustCompile(`[0-9]`)
+)
+
+// isProbableTrackingParam returns true if the given param should be stripped
+// according to the canonicalizer's tracking param heuristics, reporting it if
+// necessary.
+func (c *Canonicalizer) isProbableTrackingParam(u *url.URL, rules paramRuleSet, param string, values []string) bool {
+	if c.trackingMode == TrackingParamsIgnored || rules.allows(param) {
+		return false
+	}
+	for _, v := range values {
+		if !looksLikeTrackingToken(v) {
+			return false
+		}
+	}
+	if c.trackingReporter != nil {
+		c.trackingReporter(u, param)
+	}
+	return c.trackingMode == TrackingParamsStripped
+}
+
+// looksLikeTrackingToken returns true if the given value looks like a
+// randomly generated token.
+func looksLikeTrackingToken(v string) bool {
+	return len(v) >= minTrackingValueLength &&
+		tokenValuePattern.MatchString(v) &&
+		letterPattern.MatchString(v) &&
+		digitPattern.MatchString(v) &&
+		shannonEntropy(v) >= minTrackingValueEntropy
+}
+
+// shannonEntropy returns the Shannon entropy of the given string, in bits
+// per character.
+func shannonEntropy(s string) float64 {
+	counts := make(map[rune]int)
+	for _, r := range s {
+		counts[r]++
+	}
+	var (
+		n       = float64(len(s))
+		entropy float64
+	)
+	for _, count := range counts {
+		p := float64(count) / n
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
@@ -0,0 +1,84 @@
+package canonical
+
+import (
+	"net/url"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLooksLikeTrackingToken(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]bool{
+		"Cj0KCQjw3JanBhCPARIsAJpXTx7wXb":   true,
+		"5f2b8e9c1a7d4036b2e8f1c9a0d7e4b3": true,
+		"eyJ1IjoxMjM0NTY3OH0.aB3_xY9-Qz":   true,
+		"short1":                           false,
+		"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaa1":  false,
+		"averylongwordwithoutanydigits":    false,
+		"12345678901234567890":             false,
+		"some search terms 123456789":      false,
+	}
+	for given, want := range testCases {
+		given, want := given, want
+		t.Run(given, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, want, looksLikeTrackingToken(given))
+		})
+	}
+}
+
+func TestTrackingParamHeuristics(t *testing.T) {
+	t.Parallel()
+
+	const (
+		given = "https://www.youtube.com/watch?v=Cj0KCQjw3JanBhCPARIsAJpXTx7wXb&list=abc"
+		other = "https://example.com/foo?id=12&clk=Cj0KCQjw3JanBhCPARIsAJpXTx7wXb&tok=5f2b8e9c1a7d4036b2e8f1c9a0d7e4b3"
+	)
+
+	testCases := map[string]struct {
+		mode         TrackingParamMode
+		wantURL      string
+		wantReported []string
+	}{
+		"ignored": {
+			mode:    TrackingParamsIgnored,
+			wantURL: "https://example.com/foo?clk=Cj0KCQjw3JanBhCPARIsAJpXTx7wXb&id=12&tok=5f2b8e9c1a7d4036b2e8f1c9a0d7e4b3",
+		},
+		"reported": {
+			mode:         TrackingParamsReported,
+			wantURL:      "https://example.com/foo?clk=Cj0KCQjw3JanBhCPARIsAJpXTx7wXb&id=12&tok=5f2b8e9c1a7d4036b2e8f1c9a0d7e4b3",
+			wantReported: []string{"clk", "tok"},
+		},
+		"stripped": {
+			mode:         TrackingParamsStripped,
+			wantURL:      "https://example.com/foo?id=12",
+			wantReported: []string{"clk", "tok"},
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var reported []string
+			c, err := New(WithTrackingParamHeuristics(tc.mode, func(u *url.URL, param string) {
+				reported = append(reported, param)
+			}))
+			assert.NoError(t, err)
+
+			u, _ := url.Parse(other)
+			assert.Equal(t, tc.wantURL, c.Canonicalize(u))
+
+			// params explicitly allowed by the param rules are never
+			// considered
+			u, _ = url.Parse(given)
+			assert.Equal(t, "https://www.youtube.com/watch?list=abc&v=Cj0KCQjw3JanBhCPARIsAJpXTx7wXb", c.Canonicalize(u))
+
+			sort.Strings(reported)
+			assert.Equal(t, tc.wantReported, reported)
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package urlresolver
+
+import "context"
+
+// Chain wraps the given base resolver in the given wrappers, such that the
+// first wrapper is the outermost and sees each call first. For example,
+//
+//	Chain(resolver, Coalescing, cache)
+//
+// is equivalent to Coalescing(cache(resolver)), coalescing concurrent calls
+// for the same URL before they ever reach the cache.
+func Chain(base Interface, wrappers ...func(Interface) Interface) Interface {
+	resolver := base
+	for i := len(wrappers) - 1; i >= 0; i-- {
+		resolver = wrappers[i](resolver)
+	}
+	return resolver
+}
+
+// Coalescing wraps the given resolver so that concurrent calls to resolve
+// the same URL share a single call to the wrapped resolver, marking the
+// results of shared calls as Coalesced. A Resolver already coalesces its own
+// calls, so this is useful for resolvers that don't, or for coalescing calls
+// in front of another wrapper like a cache.
+//
+// Detailed resolutions are passed through to the wrapped resolver without
+// coalescing.
+func Coalescing(next Interface) Interface {
+	return &coalescingResolver{
+		next:      next,
+		coalescer: &coalescer{},
+	}
+}
+
+type coalescingResolver struct {
+	next      Interface
+	coalescer *coalescer
+}
+
+var _ DetailedInterface = &coalescingResolver{} // coalescingResolver implements DetailedInterface
+
+func (c *coalescingResolver) Resolve(ctx context.Context, givenURL string) (Result, error) {
+	result, coalesced, err := c.coalescer.do(ctx, coalesceKey(ctx, givenURL), givenURL, func(ctx context.Context) (Result, error) {
+		return c.next.Resolve(ctx, givenURL)
+	})
+	result.Coalesced = result.Coalesced || coalesced
+	return result, err
+}
+
+func (c *coalescingResolver) ResolveDetailed(ctx context.Context, givenURL string) (DetailedResult, error) {
+	return ResolveDetailed(ctx, c.next, givenURL)
+}
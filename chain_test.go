@@ -0,0 +1,112 @@
+package urlresolver
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChain(t *testing.T) {
+	t.Parallel()
+
+	var calls []string
+	wrapper := func(name string) func(Interface) Interface {
+		return func(next Interface) Interface {
+			return resolverFunc(func(ctx context.Context, url string) (Result, error) {
+				calls = append(calls, name)
+				return next.Resolve(ctx, url)
+			})
+		}
+	}
+	base := resolverFunc(func(_ context.Context, url string) (Result, error) {
+		calls = append(calls, "base")
+		return Result{ResolvedURL: url}, nil
+	})
+
+	result, err := Chain(base, wrapper("outer"), wrapper("inner")).Resolve(context.Background(), "https://example.com/")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/", result.ResolvedURL)
+	assert.Equal(t, []string{"outer", "inner", "base"}, calls)
+
+	static := NewStaticResolver(nil, nil)
+	assert.Same(t, static, Chain(static))
+}
+
+func TestCoalescing(t *testing.T) {
+	t.Parallel()
+
+	t.Run("concurrent calls are coalesced", func(t *testing.T) {
+		t.Parallel()
+
+		var calls atomic.Int64
+		resolver := Coalescing(resolverFunc(func(_ context.Context, url string) (Result, error) {
+			calls.Add(1)
+			<-time.After(50 * time.Millisecond)
+			return Result{ResolvedURL: url, Title: "title"}, nil
+		}))
+
+		const n = 5
+		var wg sync.WaitGroup
+		results := make([]Result, n)
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i], _ = resolver.Resolve(context.Background(), "https://example.com/")
+			}(i)
+		}
+		wg.Wait()
+
+		assert.Equal(t, int64(1), calls.Load())
+		for _, result := range results {
+			assert.Equal(t, Result{ResolvedURL: "https://example.com/", Title: "title", Coalesced: true}, result)
+		}
+	})
+
+	t.Run("sequential calls are not coalesced", func(t *testing.T) {
+		t.Parallel()
+
+		var calls atomic.Int64
+		resolver := Coalescing(resolverFunc(func(_ context.Context, url string) (Result, error) {
+			calls.Add(1)
+			return Result{ResolvedURL: url}, nil
+		}))
+
+		for i := 0; i < 2; i++ {
+			result, err := resolver.Resolve(context.Background(), "https://example.com/")
+			assert.NoError(t, err)
+			assert.False(t, result.Coalesced)
+		}
+		assert.Equal(t, int64(2), calls.Load())
+	})
+
+	t.Run("impatient caller", func(t *testing.T) {
+		t.Parallel()
+
+		resolver := Coalescing(resolverFunc(func(ctx context.Context, url string) (Result, error) {
+			<-ctx.Done()
+			return Result{ResolvedURL: url}, ctx.Err()
+		}))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		result, err := resolver.Resolve(ctx, "https://example.com/")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, "https://example.com/", result.ResolvedURL)
+	})
+
+	t.Run("detailed results pass through", func(t *testing.T) {
+		t.Parallel()
+
+		resolver := Coalescing(NewStaticResolver(map[string]Result{
+			"https://example.com/": {Title: "static"},
+		}, nil))
+		result, err := ResolveDetailed(context.Background(), resolver, "https://example.com/")
+		assert.NoError(t, err)
+		assert.Equal(t, DetailedResult{Result: Result{Title: "static"}}, result)
+	})
+}
@@ -0,0 +1,179 @@
+package urlresolver
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// sharedResolution is a resolution shared by one or more coalesced callers.
+//
+// It is also the context the resolution runs under, which carries the values
+// of the first caller's context but is only canceled once every caller has
+// given up, so that one impatient caller can't fail everyone else's
+// coalesced resolution.
+type sharedResolution struct {
+	values   context.Context
+	done     chan struct{} // closed when every caller has given up
+	finished chan struct{} // closed when the resolution has finished
+
+	// set before finished is closed
+	result Result
+	err    error
+
+	// guarded by coalescer.mu
+	waiters   int
+	callers   int
+	cancelErr error
+}
+
+var _ context.Context = &sharedResolution{} // sharedResolution implements context.Context
+
+// Deadline implements context.Context. A sharedResolution has no deadline of
+// its own; the resolver's timeout is applied to each resolution separately.
+func (s *sharedResolution) Deadline() (time.Time, bool) { return time.Time{}, false }
+
+// Done implements context.Context.
+func (s *sharedResolution) Done() <-chan struct{} { return s.done }
+
+// Err implements context.Context, returning the error of the last caller's
+// context once every caller has given up.
+func (s *sharedResolution) Err() error {
+	select {
+	case <-s.done:
+		return s.cancelErr
+	default:
+		return nil
+	}
+}
+
+// Value implements context.Context.
+func (s *sharedResolution) Value(key any) any { return s.values.Value(key) }
+
+// coalescer coalesces concurrent resolutions of the same URL into one.
+type coalescer struct {
+	mu          sync.Mutex
+	resolutions map[string]*sharedResolution
+}
+
+// join returns the in-flight resolution for the given key, or starts a new
+// one using the given function. Each call to join must be followed by a call
+// to leave.
+func (c *coalescer) join(ctx context.Context, key string, resolve func(ctx context.Context) (Result, error)) *sharedResolution {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if s, ok := c.resolutions[key]; ok {
+		s.waiters++
+		s.callers++
+		return s
+	}
+
+	s := &sharedResolution{
+		values:   context.WithoutCancel(ctx),
+		done:     make(chan struct{}),
+		finished: make(chan struct{}),
+		waiters:  1,
+		callers:  1,
+	}
+	if c.resolutions == nil {
+		c.resolutions = make(map[string]*sharedResolution)
+	}
+	c.resolutions[key] = s
+
+	go func() {
+		s.result, s.err = resolve(s)
+		// Later callers start a new resolution rather than getting this
+		// one's result.
+		c.mu.Lock()
+		if c.resolutions[key] == s {
+			delete(c.resolutions, key)
+		}
+		c.mu.Unlock()
+		close(s.finished)
+	}()
+	return s
+}
+
+// leave records that a caller is no longer waiting on the given resolution,
+// with the error of its context if it gave up early. If it was the last
+// caller, the resolution is canceled and leave returns true.
+func (c *coalescer) leave(key string, s *sharedResolution, err error) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s.waiters--
+	if s.waiters > 0 || err == nil {
+		return false
+	}
+	s.cancelErr = err
+	close(s.done)
+	if c.resolutions[key] == s {
+		delete(c.resolutions, key)
+	}
+	return true
+}
+
+// coalesced returns true if more than one caller joined the given
+// resolution.
+func (c *coalescer) coalesced(s *sharedResolution) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return s.callers > 1
+}
+
+// coalesce resolves the given canonical URL, coalescing concurrent calls for
+// the same URL into a single resolution, and reports whether the call was
+// coalesced with any others.
+//
+// The resolution runs under a context detached from any one caller and
+// bounded by the resolver's timeout.
+func (r *Resolver) coalesce(ctx context.Context, canonicalURL string) (Result, bool, error) {
+	return r.coalescer.do(ctx, coalesceKey(ctx, canonicalURL), canonicalURL, func(ctx context.Context) (Result, error) {
+		ctx, cancel := context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+		return r.doResolve(ctx, canonicalURL)
+	})
+}
+
+// do resolves the given URL using the given function, coalescing concurrent
+// calls with the same key into a single resolution, and reports whether the
+// call was coalesced with any others.
+//
+// A caller whose context is done before the resolution finishes gets an
+// error immediately, unless it is the last caller still waiting, in which
+// case the resolution is canceled and its partial result is returned.
+func (c *coalescer) do(ctx context.Context, key string, givenURL string, resolve func(ctx context.Context) (Result, error)) (Result, bool, error) {
+	s := c.join(ctx, key, resolve)
+
+	select {
+	case <-s.finished:
+		c.leave(key, s, nil)
+	case <-ctx.Done():
+		if !c.leave(key, s, ctx.Err()) {
+			result := Result{ResolvedURL: givenURL}
+			result.Outcome = classifyOutcome(result, ctx.Err())
+			return result, true, ctx.Err()
+		}
+		<-s.finished
+	}
+	return s.result, c.coalesced(s), s.err
+}
+
+// coalesceKey returns the key under which resolutions of the given canonical
+// URL are coalesced. Requests whose contexts override the resolver's
+// behavior are only coalesced with others that override it in the same way.
+func coalesceKey(ctx context.Context, canonicalURL string) string {
+	key := canonicalURL
+	if n, ok := ctx.Value(maxRedirectsKey{}).(int); ok {
+		key += "\x00max-redirects=" + strconv.Itoa(n)
+	}
+	if !titleWanted(ctx) {
+		key += "\x00without-title"
+	}
+	if detailsWanted(ctx) {
+		key += "\x00detailed"
+	}
+	return key
+}
@@ -0,0 +1,129 @@
+package urlresolver
+
+import (
+	"container/list"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// WithSharedCookieJar makes the resolver keep cookies across resolutions, so
+// that consent or session cookies set while resolving one URL are sent when
+// resolving later URLs on the same site, which can avoid repeated consent
+// interstitials.
+//
+// Cookies are partitioned by registrable domain (e.g. example.co.uk), and at
+// most maxDomains domains are kept; the least recently used domain is evicted
+// when the limit is exceeded.
+func WithSharedCookieJar(maxDomains int) Option {
+	return func(r *Resolver) {
+		r.cookieJar = newSharedCookieJar(maxDomains)
+	}
+}
+
+// sharedCookieJar is an http.CookieJar that keeps a separate cookiejar.Jar
+// for each registrable domain, bounded by LRU eviction.
+type sharedCookieJar struct {
+	maxDomains int
+
+	mu      sync.Mutex
+	domains map[string]*list.Element
+	lru     *list.List // front is most recently used
+}
+
+var _ http.CookieJar = &sharedCookieJar{} // sharedCookieJar implements http.CookieJar
+
+type sharedCookieJarEntry struct {
+	domain string
+	jar    *cookiejar.Jar
+}
+
+func newSharedCookieJar(maxDomains int) *sharedCookieJar {
+	return &sharedCookieJar{
+		maxDomains: maxDomains,
+		domains:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// SetCookies implements http.CookieJar.
+func (j *sharedCookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	if jar := j.jarFor(u, true); jar != nil {
+		jar.SetCookies(u, cookies)
+	}
+}
+
+// Cookies implements http.CookieJar.
+func (j *sharedCookieJar) Cookies(u *url.URL) []*http.Cookie {
+	if jar := j.jarFor(u, false); jar != nil {
+		return jar.Cookies(u)
+	}
+	return nil
+}
+
+// jarFor returns the jar for the given URL's registrable domain, optionally
+// creating it if it does not exist.
+func (j *sharedCookieJar) jarFor(u *url.URL, create bool) *cookiejar.Jar {
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		// e.g. IP addresses and bare public suffixes
+		domain = host
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if elem, ok := j.domains[domain]; ok {
+		j.lru.MoveToFront(elem)
+		return elem.Value.(*sharedCookieJarEntry).jar
+	}
+	if !create {
+		return nil
+	}
+
+	jar, _ := cookiejar.New(&cookiejar.Options{
+		PublicSuffixList: publicsuffix.List,
+	})
+	j.domains[domain] = j.lru.PushFront(&sharedCookieJarEntry{domain: domain, jar: jar})
+	for j.maxDomains > 0 && j.lru.Len() > j.maxDomains {
+		oldest := j.lru.Back()
+		j.lru.Remove(oldest)
+		delete(j.domains, oldest.Value.(*sharedCookieJarEntry).domain)
+	}
+	return jar
+}
+
+// lazyCookieJar is an http.CookieJar for a single fetch, which defers
+// allocating a cookiejar.Jar until a cookie is actually set, since most
+// redirect chains set no cookies at all.
+//
+// It is not safe for concurrent use, which is fine because a client follows
+// a redirect chain one request at a time.
+type lazyCookieJar struct {
+	jar *cookiejar.Jar
+}
+
+var _ http.CookieJar = &lazyCookieJar{} // lazyCookieJar implements http.CookieJar
+
+// SetCookies implements http.CookieJar.
+func (j *lazyCookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	if j.jar == nil {
+		j.jar, _ = cookiejar.New(&cookiejar.Options{
+			PublicSuffixList: publicsuffix.List,
+		})
+	}
+	j.jar.SetCookies(u, cookies)
+}
+
+// Cookies implements http.CookieJar.
+func (j *lazyCookieJar) Cookies(u *url.URL) []*http.Cookie {
+	if j.jar == nil {
+		return nil
+	}
+	return j.jar.Cookies(u)
+}
@@ -0,0 +1,119 @@
+package urlresolver
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSharedCookieJar(t *testing.T) {
+	t.Parallel()
+
+	t.Run("cookies persist across resolutions", func(t *testing.T) {
+		t.Parallel()
+
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, err := r.Cookie("consent"); err != nil {
+				http.SetCookie(w, &http.Cookie{Name: "consent", Value: "yes", Path: "/"})
+				w.Write([]byte("<title>Consent required</title>")) //nolint:errcheck
+				return
+			}
+			w.Write([]byte("<title>Article</title>")) //nolint:errcheck
+		})
+
+		for _, tc := range []struct {
+			opts       []Option
+			wantTitles []string
+		}{
+			{
+				opts:       nil,
+				wantTitles: []string{"Consent required", "Consent required"},
+			},
+			{
+				opts:       []Option{WithSharedCookieJar(10)},
+				wantTitles: []string{"Consent required", "Article"},
+			},
+		} {
+			resolver := New(newHandlerTestTransport(t, "example.com", handler), 0, tc.opts...)
+			for _, want := range tc.wantTitles {
+				result, err := resolver.Resolve(context.Background(), "https://example.com/")
+				assert.NoError(t, err)
+				assert.Equal(t, want, result.Title)
+			}
+		}
+	})
+
+	t.Run("cookies are partitioned by registrable domain", func(t *testing.T) {
+		t.Parallel()
+
+		jar := newSharedCookieJar(10)
+		jar.SetCookies(mustParseURL(t, "https://www.example.co.uk/"), []*http.Cookie{
+			{Name: "a", Value: "1", Domain: "example.co.uk"},
+		})
+
+		assert.Len(t, jar.Cookies(mustParseURL(t, "https://news.example.co.uk/")), 1)
+		assert.Len(t, jar.Cookies(mustParseURL(t, "https://other.co.uk/")), 0)
+		assert.Equal(t, 1, jar.lru.Len())
+	})
+
+	t.Run("least recently used domains are evicted", func(t *testing.T) {
+		t.Parallel()
+
+		jar := newSharedCookieJar(2)
+		cookies := []*http.Cookie{{Name: "a", Value: "1"}}
+		jar.SetCookies(mustParseURL(t, "https://a.com/"), cookies)
+		jar.SetCookies(mustParseURL(t, "https://b.com/"), cookies)
+
+		// using a.com makes b.com the least recently used domain
+		assert.Len(t, jar.Cookies(mustParseURL(t, "https://a.com/")), 1)
+		jar.SetCookies(mustParseURL(t, "https://c.com/"), cookies)
+
+		assert.Len(t, jar.Cookies(mustParseURL(t, "https://a.com/")), 1)
+		assert.Len(t, jar.Cookies(mustParseURL(t, "https://b.com/")), 0)
+		assert.Len(t, jar.Cookies(mustParseURL(t, "https://c.com/")), 1)
+	})
+}
+
+func mustParseURL(t *testing.T, rawURL string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatalf("failed to parse URL %q: %s", rawURL, err)
+	}
+	return u
+}
+
+func TestLazyCookieJar(t *testing.T) {
+	t.Parallel()
+
+	// Without a shared jar, cookies set during a redirect chain are still
+	// sent on later hops of the same chain.
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/start":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "1", Path: "/"})
+			http.Redirect(w, r, "/end", http.StatusFound)
+		default:
+			if _, err := r.Cookie("session"); err != nil {
+				w.Write([]byte("<title>No session</title>")) //nolint:errcheck
+				return
+			}
+			w.Write([]byte("<title>Session</title>")) //nolint:errcheck
+		}
+	})
+
+	resolver := New(newHandlerTestTransport(t, "example.com", handler), 0)
+	result, err := resolver.Resolve(context.Background(), "https://example.com/start")
+	assert.NoError(t, err)
+	assert.Equal(t, "Session", result.Title)
+
+	var jar lazyCookieJar
+	u, _ := url.Parse("https://example.com/")
+	assert.Nil(t, jar.Cookies(u))
+	assert.Nil(t, jar.jar)
+	jar.SetCookies(u, []*http.Cookie{{Name: "a", Value: "b"}})
+	assert.Len(t, jar.Cookies(u), 1)
+}
@@ -0,0 +1,95 @@
+package urlresolver
+
+import (
+	"context"
+	"net/http"
+)
+
+// DetailedResult is the result of resolving a URL with ResolveDetailed, which
+// extends Result with details that most callers don't need.
+type DetailedResult struct {
+	Result
+
+	// Headers holds the subset of the final response's headers listed in
+	// DetailedHeaders.
+	Headers http.Header
+
+	// Metadata describes the final HTML document, if any.
+	Metadata PageMetadata
+}
+
+// DetailedInterface defines the interface for a URL resolver that can
+// provide detailed results. Decorators (e.g. a caching resolver) that
+// implement it should pass the detailed form through unchanged.
+type DetailedInterface interface {
+	Interface
+	ResolveDetailed(ctx context.Context, url string) (DetailedResult, error)
+}
+
+var _ DetailedInterface = &Resolver{} // Resolver implements DetailedInterface
+
+// DetailedHeaders are the response headers included in a DetailedResult.
+var DetailedHeaders = []string{
+	"Cache-Control",
+	"Content-Language",
+	"Content-Length",
+	"Content-Type",
+	"ETag",
+	"Expires",
+	"Last-Modified",
+	"Server",
+	"X-Robots-Tag",
+}
+
+// resultDetails holds the extra details gathered for a detailed resolution.
+type resultDetails struct {
+	headers  http.Header
+	metadata PageMetadata
+}
+
+type detailsKey struct{}
+
+// detailsWanted returns true if the given request context was created by
+// ResolveDetailed.
+func detailsWanted(ctx context.Context) bool {
+	wanted, _ := ctx.Value(detailsKey{}).(bool)
+	return wanted
+}
+
+// ResolveDetailed resolves a URL like Resolve, additionally reporting a
+// subset of the final response's headers and metadata from the final
+// document's <head>. Finding that metadata means reading to the end of the
+// <head> rather than stopping at the <title>, which is why Resolve doesn't.
+func (r *Resolver) ResolveDetailed(ctx context.Context, givenURL string) (DetailedResult, error) {
+	result, err := r.Resolve(context.WithValue(ctx, detailsKey{}, true), givenURL)
+	detailed := DetailedResult{Result: result}
+	if details := result.details; details != nil {
+		detailed.Headers = details.headers
+		detailed.Metadata = details.metadata
+		detailed.Result.details = nil
+	}
+	return detailed, err
+}
+
+// ResolveDetailed resolves the given URL using the given resolver, via its
+// own ResolveDetailed method if it implements DetailedInterface, or else via
+// its Resolve method, in which case no extra details are available.
+func ResolveDetailed(ctx context.Context, resolver Interface, url string) (DetailedResult, error) {
+	if detailer, ok := resolver.(DetailedInterface); ok {
+		return detailer.ResolveDetailed(ctx, url)
+	}
+	result, err := resolver.Resolve(ctx, url)
+	return DetailedResult{Result: result}, err
+}
+
+// detailedHeaders returns the subset of the given headers listed in
+// DetailedHeaders.
+func detailedHeaders(header http.Header) http.Header {
+	subset := make(http.Header)
+	for _, name := range DetailedHeaders {
+		if values := header.Values(name); len(values) > 0 {
+			subset[http.CanonicalHeaderKey(name)] = append([]string(nil), values...)
+		}
+	}
+	return subset
+}
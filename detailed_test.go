@@ -0,0 +1,108 @@
+package urlresolver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveDetailed(t *testing.T) {
+	t.Parallel()
+
+	const body = `<html><head><title>Title</title><meta name="description" content="Description"><link rel="canonical" href="/article"></head><body></body></html>`
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/article", http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Write([]byte(body)) //nolint:errcheck
+	})
+
+	t.Run("detailed result", func(t *testing.T) {
+		t.Parallel()
+
+		srv := httptest.NewServer(handler)
+		defer srv.Close()
+
+		resolver := New(newSafeTestTransport(t), 0)
+		result, err := resolver.ResolveDetailed(context.Background(), srv.URL+"/redirect")
+		assert.NoError(t, err)
+		assert.Equal(t, "Title", result.Title)
+		assert.Equal(t, srv.URL+"/article", result.ResolvedURL)
+		assert.Equal(t, []string{srv.URL + "/redirect"}, result.IntermediateURLs)
+		if assert.Len(t, result.Hops, 1) {
+			assert.Greater(t, result.Hops[0].Latency, time.Duration(0))
+		}
+		assert.Equal(t, http.Header{
+			"Cache-Control":  {"max-age=60"},
+			"Content-Length": {strconv.Itoa(len(body))},
+			"Content-Type":   {"text/html; charset=utf-8"},
+		}, result.Headers)
+		assert.Equal(t, PageMetadata{Description: "Description", CanonicalURL: srv.URL + "/article"}, result.Metadata)
+		assert.Nil(t, result.Result.details)
+	})
+
+	t.Run("plain results have no details", func(t *testing.T) {
+		t.Parallel()
+
+		srv := httptest.NewServer(handler)
+		defer srv.Close()
+
+		resolver := New(newSafeTestTransport(t), 0)
+		result, err := resolver.Resolve(context.Background(), srv.URL+"/article")
+		assert.NoError(t, err)
+		assert.Equal(t, "Title", result.Title)
+		assert.Nil(t, result.details)
+	})
+
+	t.Run("detailed and plain requests are not coalesced", func(t *testing.T) {
+		t.Parallel()
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-time.After(50 * time.Millisecond)
+			handler(w, r)
+		}))
+		defer srv.Close()
+
+		resolver := New(newSafeTestTransport(t), 0)
+
+		var wg sync.WaitGroup
+		var plain Result
+		var detailed DetailedResult
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			plain, _ = resolver.Resolve(context.Background(), srv.URL+"/article")
+		}()
+		go func() {
+			defer wg.Done()
+			detailed, _ = resolver.ResolveDetailed(context.Background(), srv.URL+"/article")
+		}()
+		wg.Wait()
+
+		assert.False(t, plain.Coalesced)
+		assert.Nil(t, plain.details)
+		assert.False(t, detailed.Coalesced)
+		assert.Equal(t, "Description", detailed.Metadata.Description)
+	})
+
+	t.Run("fallback for plain resolvers", func(t *testing.T) {
+		t.Parallel()
+
+		resolver := resolverFunc(func(_ context.Context, u string) (Result, error) {
+			return Result{ResolvedURL: u, Title: "plain"}, nil
+		})
+		result, err := ResolveDetailed(context.Background(), resolver, "https://example.com/")
+		assert.NoError(t, err)
+		assert.Equal(t, DetailedResult{Result: Result{ResolvedURL: "https://example.com/", Title: "plain"}}, result)
+	})
+}
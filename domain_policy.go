@@ -0,0 +1,45 @@
+package urlresolver
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/mccutchen/urlresolver/internal/urlutil"
+)
+
+// ErrBlockedDomain is returned when a URL is not resolved because its host,
+// or the host of any redirect target, is disallowed by the resolver's domain
+// allowlist or blocklist.
+var ErrBlockedDomain = errors.New("blocked domain")
+
+// WithDomainBlocklist refuses to resolve URLs on the given domains (or any of
+// their subdomains), whether they are given directly or reached via redirect.
+func WithDomainBlocklist(domains ...string) Option {
+	return func(r *Resolver) {
+		r.domainBlocklist = append(r.domainBlocklist, urlutil.NormalizeDomains(domains)...)
+	}
+}
+
+// WithDomainAllowlist restricts resolution to URLs on the given domains (or
+// any of their subdomains), whether they are given directly or reached via
+// redirect. The blocklist takes precedence over the allowlist.
+func WithDomainAllowlist(domains ...string) Option {
+	return func(r *Resolver) {
+		r.domainAllowlist = append(r.domainAllowlist, urlutil.NormalizeDomains(domains)...)
+	}
+}
+
+// checkDomain returns an error if the given URL's host is disallowed by the
+// blocklist or allowlist.
+func checkDomain(blocklist []string, allowlist []string, u *url.URL) error {
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if urlutil.MatchDomains(host, blocklist) {
+		return fmt.Errorf("%w: %s", ErrBlockedDomain, host)
+	}
+	if len(allowlist) > 0 && !urlutil.MatchDomains(host, allowlist) {
+		return fmt.Errorf("%w: %s not in allowlist", ErrBlockedDomain, host)
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package urlresolver
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mccutchen/urlresolver/internal/urlutil"
+)
+
+func TestCheckDomain(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		blocklist []string
+		allowlist []string
+		given     string
+		wantErr   bool
+	}{
+		"no lists": {
+			given: "https://example.com/",
+		},
+		"blocked domain": {
+			blocklist: []string{"example.com"},
+			given:     "https://example.com/",
+			wantErr:   true,
+		},
+		"blocked subdomain": {
+			blocklist: []string{"example.com"},
+			given:     "https://www.EXAMPLE.com./foo",
+			wantErr:   true,
+		},
+		"blocklist requires exact suffix match": {
+			blocklist: []string{"example.com"},
+			given:     "https://badexample.com/",
+		},
+		"allowed domain": {
+			allowlist: []string{"example.com"},
+			given:     "https://www.example.com/",
+		},
+		"domain not in allowlist": {
+			allowlist: []string{"example.com"},
+			given:     "https://example.org/",
+			wantErr:   true,
+		},
+		"blocklist takes precedence": {
+			blocklist: []string{"internal.example.com"},
+			allowlist: []string{"example.com"},
+			given:     "https://internal.example.com/",
+			wantErr:   true,
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			u, err := url.Parse(tc.given)
+			assert.NoError(t, err)
+			err = checkDomain(urlutil.NormalizeDomains(tc.blocklist), urlutil.NormalizeDomains(tc.allowlist), u)
+			if tc.wantErr {
+				assert.ErrorIs(t, err, ErrBlockedDomain)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDomainPolicy(t *testing.T) {
+	t.Parallel()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://blocked.example.org/", http.StatusFound)
+	})
+
+	t.Run("given URL blocked", func(t *testing.T) {
+		t.Parallel()
+
+		resolver := New(newHandlerTestTransport(t, "example.com", handler), 0, WithDomainBlocklist("example.com"))
+		result, err := resolver.Resolve(context.Background(), "http://example.com/")
+		assert.ErrorIs(t, err, ErrBlockedDomain)
+		assert.Equal(t, Result{
+			ResolvedURL: "http://example.com/",
+			Outcome:     OutcomeBlocked,
+		}, withoutHops(t, result))
+	})
+
+	t.Run("redirect target blocked", func(t *testing.T) {
+		t.Parallel()
+
+		resolver := New(newHandlerTestTransport(t, "example.com", handler), 0, WithDomainBlocklist("example.org"))
+		result, err := resolver.Resolve(context.Background(), "http://example.com/")
+		assert.True(t, errors.Is(err, ErrBlockedDomain), "expected ErrBlockedDomain, got %v", err)
+		assert.Equal(t, Result{
+			ResolvedURL:      "https://blocked.example.org/",
+			IntermediateURLs: []string{"http://example.com/"},
+			Outcome:          OutcomeBlocked,
+		}, withoutHops(t, result))
+	})
+
+	t.Run("redirect target not in allowlist", func(t *testing.T) {
+		t.Parallel()
+
+		resolver := New(newHandlerTestTransport(t, "example.com", handler), 0, WithDomainAllowlist("example.com"))
+		result, err := resolver.Resolve(context.Background(), "http://example.com/")
+		assert.True(t, errors.Is(err, ErrBlockedDomain), "expected ErrBlockedDomain, got %v", err)
+		assert.Equal(t, Result{
+			ResolvedURL:      "https://blocked.example.org/",
+			IntermediateURLs: []string{"http://example.com/"},
+			Outcome:          OutcomeBlocked,
+		}, withoutHops(t, result))
+	})
+
+	t.Run("tweet URL blocked", func(t *testing.T) {
+		t.Parallel()
+
+		fetcher := &testTweetFetcher{
+			fetch: func(context.Context, string) (Tweet, error) {
+				t.Error("tweet fetcher should not be called")
+				return Tweet{}, nil
+			},
+		}
+		resolver := New(newHandlerTestTransport(t, "example.com", handler), 0, WithDomainBlocklist("twitter.com"), WithTweetFetcher(fetcher))
+		result, err := resolver.Resolve(context.Background(), "https://twitter.com/username/status/1234")
+		assert.ErrorIs(t, err, ErrBlockedDomain)
+		assert.Equal(t, Result{
+			ResolvedURL: "https://twitter.com/username/status/1234",
+			Outcome:     OutcomeBlocked,
+		}, withoutHops(t, result))
+	})
+
+	t.Run("shortener blocked before unwrapping", func(t *testing.T) {
+		t.Parallel()
+
+		unwrapper := &testUnwrapper{unwrap: func(*url.URL) (*url.URL, bool) {
+			t.Error("unwrapper should not be called")
+			return nil, false
+		}}
+		resolver := New(newHandlerTestTransport(t, "example.com", handler), 0, WithDomainBlocklist("bit.ly"), WithUnwrappers(unwrapper))
+		result, err := resolver.Resolve(context.Background(), "https://bit.ly/abc")
+		assert.ErrorIs(t, err, ErrBlockedDomain)
+		assert.Equal(t, Result{
+			ResolvedURL: "https://bit.ly/abc",
+			Outcome:     OutcomeBlocked,
+		}, withoutHops(t, result))
+	})
+
+	t.Run("unwrapped target blocked", func(t *testing.T) {
+		t.Parallel()
+
+		unwrapper := &testUnwrapper{unwrap: func(*url.URL) (*url.URL, bool) {
+			target, _ := url.Parse("https://blocked.example.org/")
+			return target, true
+		}}
+		resolver := New(newHandlerTestTransport(t, "example.com", handler), 0, WithDomainBlocklist("example.org"), WithUnwrappers(unwrapper))
+		result, err := resolver.Resolve(context.Background(), "https://bit.ly/abc")
+		assert.ErrorIs(t, err, ErrBlockedDomain)
+		assert.Equal(t, Result{
+			ResolvedURL:      "https://blocked.example.org/",
+			IntermediateURLs: []string{"https://bit.ly/abc"},
+			Outcome:          OutcomeBlocked,
+		}, withoutHops(t, result))
+	})
+
+	t.Run("sailthru target blocked", func(t *testing.T) {
+		t.Parallel()
+
+		encodedURL := base64.RawURLEncoding.EncodeToString([]byte("https://blocked.example.org/"))
+		givenURL := "https://link.example.com/click/00000000.0000/" + encodedURL + "/0000"
+
+		resolver := New(newHandlerTestTransport(t, "example.com", handler), 0, WithDomainBlocklist("example.org"))
+		result, err := resolver.Resolve(context.Background(), givenURL)
+		assert.ErrorIs(t, err, ErrBlockedDomain)
+		assert.Equal(t, Result{
+			ResolvedURL:      "https://blocked.example.org/",
+			IntermediateURLs: []string{givenURL},
+			Outcome:          OutcomeBlocked,
+		}, withoutHops(t, result))
+	})
+
+	t.Run("robots.txt redirect blocked", func(t *testing.T) {
+		t.Parallel()
+
+		var blockedRequests atomic.Int64
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/robots.txt" {
+				http.Redirect(w, r, "https://blocked.example.org/robots.txt", http.StatusFound)
+				return
+			}
+			w.Write([]byte("<title>title</title>")) //nolint:errcheck
+		})
+		transport := &testTransport{
+			roundTrip: func(r *http.Request) (*http.Response, error) {
+				if r.URL.Host != "example.com" {
+					blockedRequests.Add(1)
+					return nil, errors.New("unexpected request")
+				}
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, r)
+				resp := rec.Result()
+				resp.Request = r
+				return resp, nil
+			},
+		}
+		resolver := New(transport, 0, WithDomainBlocklist("example.org"), WithRobotsTxt("testbot"))
+		result, err := resolver.Resolve(context.Background(), "https://example.com/")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com/", result.ResolvedURL)
+		assert.Equal(t, int64(0), blockedRequests.Load())
+	})
+}
@@ -1,7 +1,12 @@
 package fakebrowser
 
 import (
+	"crypto/tls"
+	"fmt"
 	"net/http"
+	"strings"
+
+	utls "github.com/refraction-networking/utls"
 )
 
 // DefaultHeaders defines the headers that will be injected into every outgoing
@@ -16,12 +21,37 @@ var DefaultHeaders = map[string]string{
 	"User-Agent":      "Mozilla/5.0 (Macintosh; Intel Mac OS X 10.15; rv:130.0) Gecko/20100101 Firefox/130.0",
 }
 
+// BotHeaders returns a set of headers that honestly identify the client as a
+// bot, for deployments that must not spoof a real web browser. The product
+// and contact URL are combined into a User-Agent following the common
+// convention for well-behaved crawlers, e.g.:
+//
+//	urlresolver/1.0 (+https://urlresolver.com/bot)
+//
+// The result may be given to WithHeaders or WithDomainHeaders.
+func BotHeaders(product string, contactURL string) map[string]string {
+	return map[string]string{
+		"Accept":          "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+		"Accept-Language": "en-US,en;q=0.5",
+		"User-Agent":      fmt.Sprintf("%s (+%s)", product, contactURL),
+	}
+}
+
 // Transport is an http.RoundTripper implementation that injects a set of
 // headers into every outgoing request in order to fake the appearance of a
 // real web browser making the request.
 type Transport struct {
 	transport     http.RoundTripper
 	injectHeaders map[string]string
+	domainHeaders map[string]map[string]string
+
+	// optional HTTP/1.1-only transport used for some or all hosts
+	http1Transport http.RoundTripper
+	http1Domains   []string
+	http1All       bool
+
+	// optional browser-like TLS ClientHello
+	tlsFingerprint *utls.ClientHelloID
 }
 
 var _ http.RoundTripper = &Transport{} // Transport implements http.RoundTripper
@@ -35,6 +65,12 @@ func New(transport http.RoundTripper, opts ...Option) *Transport {
 	for _, opt := range opts {
 		opt(t)
 	}
+	if t.tlsFingerprint != nil {
+		t.transport = newFingerprintTransport(t.transport, *t.tlsFingerprint)
+	}
+	if t.http1All || len(t.http1Domains) > 0 {
+		t.http1Transport = newHTTP1Transport(t.transport)
+	}
 	return t
 }
 
@@ -42,14 +78,44 @@ func New(transport http.RoundTripper, opts ...Option) *Transport {
 // headers into the outgoing request.
 func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 	// existing headers take precedence over injected headers
-	for key, value := range t.injectHeaders {
+	for key, value := range t.headersFor(req.URL.Hostname()) {
 		if req.Header.Get(key) == "" {
 			req.Header.Set(key, value)
 		}
 	}
+	if t.http1Transport != nil && t.useHTTP1(req.URL.Hostname()) {
+		return t.http1Transport.RoundTrip(req)
+	}
 	return t.transport.RoundTrip(req)
 }
 
+// useHTTP1 returns true if requests to the given host must use HTTP/1.1.
+func (t *Transport) useHTTP1(host string) bool {
+	if t.http1All {
+		return true
+	}
+	host = strings.ToLower(host)
+	for _, domain := range t.http1Domains {
+		if matchDomain(host, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// headersFor returns the set of headers to inject into requests to the given
+// host, preferring the most specific matching domain override.
+func (t *Transport) headersFor(host string) map[string]string {
+	host = strings.ToLower(host)
+	headers, matched := t.injectHeaders, ""
+	for domain, domainHeaders := range t.domainHeaders {
+		if len(domain) > len(matched) && matchDomain(host, domain) {
+			headers, matched = domainHeaders, domain
+		}
+	}
+	return headers
+}
+
 // Option customizes a Transport.
 type Option func(*Transport)
 
@@ -59,3 +125,57 @@ func WithHeaders(injectHeaders map[string]string) Option {
 		t.injectHeaders = injectHeaders
 	}
 }
+
+// WithDomainHeaders overrides the set of headers injected into requests to
+// the given domain and any of its subdomains, allowing different modes (e.g.
+// BotHeaders and DefaultHeaders) to be mixed on a per-domain basis.
+func WithDomainHeaders(domain string, injectHeaders map[string]string) Option {
+	return func(t *Transport) {
+		if t.domainHeaders == nil {
+			t.domainHeaders = make(map[string]map[string]string)
+		}
+		t.domainHeaders[strings.ToLower(domain)] = injectHeaders
+	}
+}
+
+// WithHTTP1 forces requests to the given domains (and their subdomains) to
+// use HTTP/1.1, for hosts whose bot detection treats HTTP/2 clients
+// differently. If no domains are given, HTTP/1.1 is used for every request.
+//
+// This only takes effect if the wrapped transport is an *http.Transport,
+// which is cloned with HTTP/2 disabled; HTTP/2 settings for all other hosts
+// are left as configured on the wrapped transport. For any other transport,
+// this option silently does nothing, and requests use whichever protocol the
+// wrapped transport negotiates.
+func WithHTTP1(domains ...string) Option {
+	return func(t *Transport) {
+		if len(domains) == 0 {
+			t.http1All = true
+		}
+		for _, domain := range domains {
+			t.http1Domains = append(t.http1Domains, strings.ToLower(domain))
+		}
+	}
+}
+
+// newHTTP1Transport returns a copy of the given transport with HTTP/2
+// disabled, or nil if the transport cannot be copied.
+func newHTTP1Transport(transport http.RoundTripper) http.RoundTripper {
+	httpTransport, ok := transport.(*http.Transport)
+	if !ok {
+		return nil
+	}
+	t := httpTransport.Clone()
+	t.ForceAttemptHTTP2 = false
+	// a non-nil, empty TLSNextProto map disables HTTP/2
+	t.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	if t.TLSClientConfig == nil {
+		t.TLSClientConfig = &tls.Config{}
+	}
+	t.TLSClientConfig.NextProtos = []string{"http/1.1"}
+	return t
+}
+
+func matchDomain(host string, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
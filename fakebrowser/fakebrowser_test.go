@@ -2,6 +2,7 @@
 package fakebrowser
 
 import (
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -68,6 +69,23 @@ func TestHeaderInjection(t *testing.T) {
 				"X-2":             "in request",
 			}),
 		},
+		"bot headers can be injected": {
+			transport: New(http.DefaultTransport, WithHeaders(BotHeaders("urlresolver/1.0", "https://example.com/bot"))),
+			wantHeaders: mergeMaps(BotHeaders("urlresolver/1.0", "https://example.com/bot"), map[string]string{
+				"Accept-Encoding": "gzip", // added by stdlib http client
+				"User-Agent":      "urlresolver/1.0 (+https://example.com/bot)",
+			}),
+		},
+		"domain headers override injected headers": {
+			transport: New(
+				http.DefaultTransport,
+				WithHeaders(BotHeaders("urlresolver/1.0", "https://example.com/bot")),
+				WithDomainHeaders("127.0.0.1", DefaultHeaders),
+			),
+			wantHeaders: mergeMaps(DefaultHeaders, map[string]string{
+				"Accept-Encoding": "gzip", // added by stdlib http client
+			}),
+		},
 	}
 	for name, tc := range testCases {
 		tc := tc
@@ -97,3 +115,94 @@ func TestHeaderInjection(t *testing.T) {
 		})
 	}
 }
+
+func TestHeadersFor(t *testing.T) {
+	t.Parallel()
+
+	var (
+		defaultHeaders   = map[string]string{"X-Mode": "default"}
+		domainHeaders    = map[string]string{"X-Mode": "domain"}
+		subdomainHeaders = map[string]string{"X-Mode": "subdomain"}
+	)
+	transport := New(
+		http.DefaultTransport,
+		WithHeaders(defaultHeaders),
+		WithDomainHeaders("Example.com", domainHeaders),
+		WithDomainHeaders("news.example.com", subdomainHeaders),
+	)
+
+	testCases := map[string]map[string]string{
+		"example.com":          domainHeaders,
+		"EXAMPLE.COM":          domainHeaders,
+		"www.example.com":      domainHeaders,
+		"news.example.com":     subdomainHeaders,
+		"www.news.example.com": subdomainHeaders,
+		"badexample.com":       defaultHeaders,
+		"example.com.evil.com": defaultHeaders,
+		"other.org":            defaultHeaders,
+	}
+	for host, want := range testCases {
+		assert.Equal(t, want, transport.headersFor(host), host)
+	}
+}
+
+func TestHTTP1(t *testing.T) {
+	t.Parallel()
+
+	newServer := func(t *testing.T) *httptest.Server {
+		srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.Proto)) //nolint:errcheck
+		}))
+		srv.EnableHTTP2 = true
+		srv.StartTLS()
+		t.Cleanup(srv.Close)
+		return srv
+	}
+
+	getProto := func(t *testing.T, transport http.RoundTripper, url string) string {
+		resp, err := (&http.Client{Transport: transport}).Get(url)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		return string(body)
+	}
+
+	testCases := map[string]struct {
+		opts      []Option
+		wantProto string
+	}{
+		"http2 by default": {
+			wantProto: "HTTP/2.0",
+		},
+		"http1 for all hosts": {
+			opts:      []Option{WithHTTP1()},
+			wantProto: "HTTP/1.1",
+		},
+		"http1 for matching domain": {
+			opts:      []Option{WithHTTP1("127.0.0.1")},
+			wantProto: "HTTP/1.1",
+		},
+		"http2 for other domains": {
+			opts:      []Option{WithHTTP1("example.com")},
+			wantProto: "HTTP/2.0",
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			srv := newServer(t)
+			transport := New(srv.Client().Transport, tc.opts...)
+			assert.Equal(t, tc.wantProto, getProto(t, transport, srv.URL))
+		})
+	}
+
+	t.Run("no-op for other transports", func(t *testing.T) {
+		t.Parallel()
+		srv := newServer(t)
+		transport := New(&Transport{transport: srv.Client().Transport}, WithHTTP1())
+		assert.Nil(t, transport.http1Transport)
+		assert.Equal(t, "HTTP/2.0", getProto(t, transport, srv.URL))
+	})
+}
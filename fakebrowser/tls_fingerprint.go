@@ -0,0 +1,107 @@
+package fakebrowser
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+
+	utls "github.com/refraction-networking/utls"
+)
+
+// WithTLSFingerprint makes TLS connections using a ClientHello that mimics a
+// real web browser, e.g. utls.HelloChrome_Auto, so that bot detection based on
+// TLS fingerprints (JA3 and similar) sees a browser rather than a Go client.
+//
+// This only takes effect if the wrapped transport is an *http.Transport. Its
+// TLS connections are made on top of connections from its own DialContext,
+// so any checks applied by its dialer (e.g. safedialer.Control, as
+// recommended in the README) still apply. Its root CAs, server name, and
+// certificate verification settings are respected, but any DialTLSContext
+// is replaced.
+//
+// Connections made with a browser fingerprint only support HTTP/1.1.
+func WithTLSFingerprint(helloID utls.ClientHelloID) Option {
+	return func(t *Transport) {
+		t.tlsFingerprint = &helloID
+	}
+}
+
+// newFingerprintTransport returns a copy of the given transport that dials
+// TLS connections using the given ClientHello, or the given transport
+// unchanged if it cannot be copied.
+func newFingerprintTransport(transport http.RoundTripper, helloID utls.ClientHelloID) http.RoundTripper {
+	httpTransport, ok := transport.(*http.Transport)
+	if !ok {
+		return transport
+	}
+	t := httpTransport.Clone()
+
+	dial := t.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	tlsConfig := t.TLSClientConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+
+	t.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return dialTLSFingerprint(ctx, dial, tlsConfig, helloID, network, addr)
+	}
+	// connections returned by DialTLSContext are not *tls.Conn, so
+	// net/http cannot negotiate HTTP/2 over them
+	t.ForceAttemptHTTP2 = false
+	t.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	return t
+}
+
+func dialTLSFingerprint(
+	ctx context.Context,
+	dial func(context.Context, string, string) (net.Conn, error),
+	tlsConfig *tls.Config,
+	helloID utls.ClientHelloID,
+	network string,
+	addr string,
+) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	// the spec is mutated by the handshake, so we need a fresh copy for each
+	// connection
+	spec, err := utls.UTLSIdToSpec(helloID)
+	if err != nil {
+		return nil, err
+	}
+	for _, ext := range spec.Extensions {
+		if alpn, ok := ext.(*utls.ALPNExtension); ok {
+			alpn.AlpnProtocols = []string{"http/1.1"}
+		}
+	}
+
+	serverName := tlsConfig.ServerName
+	if serverName == "" {
+		serverName = host
+	}
+
+	conn, err := dial(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	uconn := utls.UClient(conn, &utls.Config{
+		ServerName:         serverName,
+		RootCAs:            tlsConfig.RootCAs,
+		InsecureSkipVerify: tlsConfig.InsecureSkipVerify, //nolint:gosec
+	}, utls.HelloCustom)
+	if err := uconn.ApplyPreset(&spec); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := uconn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return uconn, nil
+}
@@ -0,0 +1,113 @@
+package fakebrowser
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	utls "github.com/refraction-networking/utls"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTLSFingerprint(t *testing.T) {
+	t.Parallel()
+
+	// newServer returns a TLS server that records whether each ClientHello
+	// it receives includes GREASE values, which browsers send and Go does
+	// not.
+	newServer := func(t *testing.T, sawGREASE *atomic.Bool) *httptest.Server {
+		srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.Proto)) //nolint:errcheck
+		}))
+		srv.EnableHTTP2 = true
+		srv.TLS = &tls.Config{
+			GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+				for _, suite := range hello.CipherSuites {
+					if isGREASE(suite) {
+						sawGREASE.Store(true)
+					}
+				}
+				return nil, nil
+			},
+		}
+		srv.StartTLS()
+		t.Cleanup(srv.Close)
+		return srv
+	}
+
+	get := func(t *testing.T, transport http.RoundTripper, url string) string {
+		resp, err := (&http.Client{Transport: transport}).Get(url)
+		if !assert.NoError(t, err) {
+			return ""
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		return string(body)
+	}
+
+	t.Run("default go fingerprint", func(t *testing.T) {
+		t.Parallel()
+		var sawGREASE atomic.Bool
+		srv := newServer(t, &sawGREASE)
+
+		assert.Equal(t, "HTTP/2.0", get(t, New(srv.Client().Transport), srv.URL))
+		assert.False(t, sawGREASE.Load())
+	})
+
+	t.Run("browser fingerprint", func(t *testing.T) {
+		t.Parallel()
+		var sawGREASE atomic.Bool
+		srv := newServer(t, &sawGREASE)
+
+		transport := New(srv.Client().Transport, WithTLSFingerprint(utls.HelloChrome_Auto))
+		assert.Equal(t, "HTTP/1.1", get(t, transport, srv.URL))
+		assert.True(t, sawGREASE.Load())
+	})
+
+	t.Run("dialer is still used", func(t *testing.T) {
+		t.Parallel()
+		var sawGREASE atomic.Bool
+		srv := newServer(t, &sawGREASE)
+
+		var dials atomic.Int32
+		base := srv.Client().Transport.(*http.Transport).Clone()
+		base.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			dials.Add(1)
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		}
+
+		transport := New(base, WithTLSFingerprint(utls.HelloChrome_Auto))
+		assert.Equal(t, "HTTP/1.1", get(t, transport, srv.URL))
+		assert.Equal(t, int32(1), dials.Load())
+	})
+
+	t.Run("certificates are still verified", func(t *testing.T) {
+		t.Parallel()
+		var sawGREASE atomic.Bool
+		srv := newServer(t, &sawGREASE)
+
+		// http.DefaultTransport does not trust the test server's certificate
+		transport := New(http.DefaultTransport.(*http.Transport).Clone(), WithTLSFingerprint(utls.HelloChrome_Auto))
+		_, err := (&http.Client{Transport: transport}).Get(srv.URL)
+		assert.Error(t, err)
+	})
+
+	t.Run("no-op for other transports", func(t *testing.T) {
+		t.Parallel()
+		inner := &Transport{transport: http.DefaultTransport}
+		transport := New(inner, WithTLSFingerprint(utls.HelloChrome_Auto))
+		assert.Same(t, inner, transport.transport)
+	})
+}
+
+// isGREASE returns true if the given value is one of the reserved GREASE
+// values from RFC 8701.
+func isGREASE(v uint16) bool {
+	return v&0x0f0f == 0x0a0a && v>>8 == v&0xff
+}
@@ -0,0 +1,175 @@
+package urlresolver
+
+import (
+	"bytes"
+	"encoding/xml"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// FeedInfo describes an RSS or Atom feed found at the end of resolution,
+// whose title is reported as the result's title.
+type FeedInfo struct {
+	// Format is either "rss" (including RSS 1.0) or "atom".
+	Format string
+
+	// Link is the URL of the website the feed belongs to, if given.
+	Link string
+}
+
+// feedMediaTypes are the media types that may hold a feed. Generic XML is
+// only treated as a feed if its root element turns out to be one.
+var feedMediaTypes = map[string]bool{
+	"application/rss+xml":  true,
+	"application/atom+xml": true,
+	"application/rdf+xml":  true,
+	"application/xml":      true,
+	"text/xml":             true,
+}
+
+// atomNamespace is the XML namespace of Atom elements.
+const atomNamespace = "http://www.w3.org/2005/Atom"
+
+// feedItemRegex matches the start of a feed's first item or entry, by which
+// point its own title and link should have been read.
+var feedItemRegex = regexp.MustCompile(`(?i)<(item|entry)[\s/>]`)
+
+// isFeedResponse returns true if the given response may hold a feed.
+func isFeedResponse(resp *http.Response) bool {
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return err == nil && feedMediaTypes[mediaType]
+}
+
+// maybeParseFeed returns the title and details of the feed in the given
+// response, or a nil *FeedInfo if it does not hold a feed. Like other
+// non-HTML responses, feeds are parsed on a best effort basis, so errors
+// reading the body just mean that no feed is found.
+func (r *Resolver) maybeParseFeed(resp *http.Response) (string, *FeedInfo) {
+	buf := r.pool.Get()
+	defer r.pool.Put(buf)
+
+	body, err := decompressBody(resp)
+	if err != nil {
+		return "", nil
+	}
+	defer body.Close()
+
+	// A read error may still leave us with enough of the feed to parse,
+	// e.g. when a partial response cuts off a compressed body.
+	readHead(buf, body, r.maxBodySize, feedItemRegex) //nolint:errcheck
+	r.metrics.bodyRead(buf.Len())
+
+	title, feed := parseFeed(buf.Bytes())
+	if feed == nil {
+		return "", nil
+	}
+	if feed.Link != "" {
+		if link, err := resp.Request.URL.Parse(feed.Link); err == nil {
+			feed.Link = link.String()
+		}
+	}
+	r.metrics.titleExtracted(title, nil)
+	return title, feed
+}
+
+// parseFeed parses as much of the given (possibly truncated) feed as is
+// needed to find its title and link.
+func parseFeed(body []byte) (string, *FeedInfo) {
+	dec := xml.NewDecoder(bytes.NewReader(bytes.TrimPrefix(body, utf8BOM)))
+	dec.CharsetReader = charset.NewReaderLabel
+	dec.Strict = false
+	dec.Entity = xml.HTMLEntity
+
+	var (
+		feed  *FeedInfo
+		title string
+		path  []string // local names of the currently open elements
+	)
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			// EOF, or a truncated or malformed document
+			return title, feed
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if feed == nil {
+				switch t.Name.Local {
+				case "rss", "RDF":
+					feed = &FeedInfo{Format: "rss"}
+				case "feed":
+					feed = &FeedInfo{Format: "atom"}
+				default:
+					return "", nil
+				}
+			}
+			if t.Name.Local == "item" || t.Name.Local == "entry" {
+				return title, feed
+			}
+			if !isFeedMetadata(feed, path) {
+				path = append(path, t.Name.Local)
+				continue
+			}
+
+			switch {
+			case t.Name.Local == "title" && title == "":
+				var text string
+				if dec.DecodeElement(&text, &t) != nil {
+					return title, feed
+				}
+				title = strings.TrimSpace(text)
+			case t.Name.Local == "link" && feed.Link == "":
+				var text string
+				if dec.DecodeElement(&text, &t) != nil {
+					return title, feed
+				}
+				feed.Link = feedLink(feed, t, text)
+			default:
+				path = append(path, t.Name.Local)
+			}
+		case xml.EndElement:
+			if len(path) > 0 {
+				path = path[:len(path)-1]
+			}
+		}
+	}
+}
+
+// isFeedMetadata returns true if the given path of open elements is the one
+// containing a feed's own metadata, as opposed to that of its items.
+func isFeedMetadata(feed *FeedInfo, path []string) bool {
+	if feed.Format == "atom" {
+		return len(path) == 1
+	}
+	return len(path) == 2 && path[1] == "channel"
+}
+
+// feedLink returns the website link given by the given <link> element with
+// the given text content, if any.
+func feedLink(feed *FeedInfo, link xml.StartElement, text string) string {
+	if feed.Format == "rss" {
+		// RSS feeds may also include an Atom <link> to themselves
+		if link.Name.Space == atomNamespace {
+			return ""
+		}
+		return strings.TrimSpace(text)
+	}
+
+	var href, rel string
+	for _, attr := range link.Attr {
+		switch attr.Name.Local {
+		case "href":
+			href = attr.Value
+		case "rel":
+			rel = attr.Value
+		}
+	}
+	if rel != "" && rel != "alternate" {
+		return ""
+	}
+	return strings.TrimSpace(href)
+}
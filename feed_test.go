@@ -0,0 +1,181 @@
+package urlresolver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/encoding/charmap"
+)
+
+const rssFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">
+  <channel>
+    <atom:link href="https://example.com/feed.xml" rel="self" type="application/rss+xml"/>
+    <title><![CDATA[Example Blog & Friends]]></title>
+    <link>https://example.com/</link>
+    <image>
+      <title>Example Blog logo</title>
+      <link>https://example.com/logo</link>
+    </image>
+    <item>
+      <title>First post</title>
+      <link>https://example.com/first</link>
+    </item>
+  </channel>
+</rss>`
+
+const atomFeed = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <link href="/atom.xml" rel="self"/>
+  <link href="/blog/"/>
+  <author><name>Someone</name></author>
+  <title type="text">Example Atom &amp; Feed</title>
+  <entry>
+    <title>First entry</title>
+    <link href="/first"/>
+  </entry>
+</feed>`
+
+const rdfFeed = `<?xml version="1.0"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/">
+  <channel rdf:about="https://example.com/">
+    <title>Example RDF Feed</title>
+    <link>https://example.com/</link>
+  </channel>
+  <item rdf:about="https://example.com/first">
+    <title>First item</title>
+  </item>
+</rdf:RDF>`
+
+func TestParseFeed(t *testing.T) {
+	t.Parallel()
+
+	latin1, _ := charmap.ISO8859_1.NewEncoder().Bytes([]byte(`<?xml version="1.0" encoding="ISO-8859-1"?><rss><channel><title>Iñtërnâtiônàlizætiøn</title></channel></rss>`))
+
+	testCases := map[string]struct {
+		body      []byte
+		wantTitle string
+		wantFeed  *FeedInfo
+	}{
+		"rss": {
+			body:      []byte(rssFeed),
+			wantTitle: "Example Blog & Friends",
+			wantFeed:  &FeedInfo{Format: "rss", Link: "https://example.com/"},
+		},
+		"atom": {
+			body:      []byte(atomFeed),
+			wantTitle: "Example Atom & Feed",
+			wantFeed:  &FeedInfo{Format: "atom", Link: "/blog/"},
+		},
+		"rss 1.0": {
+			body:      []byte(rdfFeed),
+			wantTitle: "Example RDF Feed",
+			wantFeed:  &FeedInfo{Format: "rss", Link: "https://example.com/"},
+		},
+		"declared encoding": {
+			body:      latin1,
+			wantTitle: "Iñtërnâtiônàlizætiøn",
+			wantFeed:  &FeedInfo{Format: "rss"},
+		},
+		"byte order mark": {
+			body:      append([]byte("\xEF\xBB\xBF"), rssFeed...),
+			wantTitle: "Example Blog & Friends",
+			wantFeed:  &FeedInfo{Format: "rss", Link: "https://example.com/"},
+		},
+		"html entities": {
+			body:      []byte(`<rss><channel><title>Caf&eacute;&nbsp;News</title></channel></rss>`),
+			wantTitle: "Café News",
+			wantFeed:  &FeedInfo{Format: "rss"},
+		},
+		"no title before first item": {
+			body:     []byte(`<rss><channel><item><title>Item</title></item><title>Too late</title></channel></rss>`),
+			wantFeed: &FeedInfo{Format: "rss"},
+		},
+		"truncated": {
+			body:      []byte(rssFeed[:strings.Index(rssFeed, "<link>")]),
+			wantTitle: "Example Blog & Friends",
+			wantFeed:  &FeedInfo{Format: "rss"},
+		},
+		"not a feed": {
+			body: []byte(`<?xml version="1.0"?><sitemap><title>Nope</title></sitemap>`),
+		},
+		"not xml": {
+			body: []byte(`{"title": "Nope"}`),
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			title, feed := parseFeed(tc.body)
+			assert.Equal(t, tc.wantTitle, title)
+			assert.Equal(t, tc.wantFeed, feed)
+		})
+	}
+}
+
+func TestFeedResolution(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		contentType string
+		body        string
+		want        Result
+	}{
+		"rss": {
+			contentType: "application/rss+xml; charset=utf-8",
+			body:        rssFeed,
+			want: Result{
+				Title:      "Example Blog & Friends",
+				Outcome:    OutcomeOK,
+				StatusCode: 200,
+				Feed:       &FeedInfo{Format: "rss", Link: "https://example.com/"},
+			},
+		},
+		"atom served as generic xml": {
+			contentType: "text/xml",
+			body:        atomFeed,
+			want: Result{
+				Title:      "Example Atom & Feed",
+				Outcome:    OutcomeOK,
+				StatusCode: 200,
+				Feed:       &FeedInfo{Format: "atom", Link: "/blog/"},
+			},
+		},
+		"other xml is a file": {
+			contentType: "application/xml",
+			body:        `<?xml version="1.0"?><sitemap></sitemap>`,
+			want: Result{
+				Outcome:    OutcomeFile,
+				StatusCode: 200,
+				File:       &FileInfo{Name: "feed.xml", MIMEType: "application/xml", Size: 40},
+			},
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tc.contentType)
+				w.Write([]byte(tc.body)) //nolint:errcheck
+			}))
+			defer srv.Close()
+
+			resolver := New(newSafeTestTransport(t), 0)
+			result, err := resolver.Resolve(context.Background(), srv.URL+"/feed.xml")
+			assert.NoError(t, err)
+
+			tc.want.ResolvedURL = srv.URL + "/feed.xml"
+			if tc.want.Feed != nil && tc.want.Feed.Link != "" {
+				tc.want.Feed.Link = renderURL(srv.URL, tc.want.Feed.Link)
+			}
+			assert.Equal(t, tc.want, result)
+		})
+	}
+}
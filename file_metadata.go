@@ -0,0 +1,91 @@
+package urlresolver
+
+import (
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// FileInfo describes a non-HTML resource found at the end of resolution, so
+// that consumers can present e.g. "report.pdf (2.3 MB)" in place of a title.
+type FileInfo struct {
+	// Name is the filename suggested by the Content-Disposition header, or
+	// else the last segment of the resolved URL's path, if any.
+	Name string
+
+	// MIMEType is the media type from the Content-Type header, without any
+	// parameters.
+	MIMEType string
+
+	// Size is the size of the resource in bytes, or -1 if unknown.
+	Size int64
+
+	// Image describes the resource if it is an image in a supported format.
+	Image *ImageInfo
+}
+
+// isFileResponse returns true if the given response is a non-HTML resource
+// whose metadata should be reported instead of a title.
+func isFileResponse(resp *http.Response) bool {
+	if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		return false
+	}
+	return !shouldParseTitle(resp)
+}
+
+// fileInfo extracts metadata about the resource in the given response.
+func fileInfo(resp *http.Response) *FileInfo {
+	info := &FileInfo{
+		Name: fileName(resp),
+		Size: fileSize(resp),
+	}
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil {
+		info.MIMEType = mediaType
+	}
+	return info
+}
+
+// fileName returns the filename given in the response's Content-Disposition
+// header, falling back to the last segment of the request path.
+func fileName(resp *http.Response) string {
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		// A filename may not safely include any directories
+		if name := path.Base(strings.ReplaceAll(params["filename"], `\`, "/")); name != "." && name != "/" {
+			return name
+		}
+	}
+	if resp.Request == nil || resp.Request.URL == nil {
+		return ""
+	}
+	name := path.Base(resp.Request.URL.Path)
+	if name == "." || name == "/" {
+		return ""
+	}
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		return unescaped
+	}
+	return name
+}
+
+// fileSize returns the full size of the resource in the given response. Our
+// Range request means that the body may be only part of the resource, in
+// which case the size is taken from the Content-Range header.
+func fileSize(resp *http.Response) int64 {
+	if resp.StatusCode == http.StatusPartialContent {
+		// e.g. Content-Range: bytes 0-1023/146515
+		contentRange := resp.Header.Get("Content-Range")
+		if i := strings.LastIndexByte(contentRange, '/'); i != -1 {
+			if size, err := strconv.ParseInt(contentRange[i+1:], 10, 64); err == nil && size >= 0 {
+				return size
+			}
+		}
+		return -1
+	}
+	if resp.ContentLength < 0 {
+		return -1
+	}
+	return resp.ContentLength
+}
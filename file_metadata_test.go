@@ -0,0 +1,163 @@
+package urlresolver
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileMetadata(t *testing.T) {
+	t.Parallel()
+
+	content := bytes.Repeat([]byte("%PDF"), 1024*1024)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/download":
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Header().Set("Content-Disposition", `attachment; filename="report.pdf"`)
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+		case "/files/annual report.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Write(content[:1024]) //nolint:errcheck
+		default:
+			http.Redirect(w, r, "/download", http.StatusFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	resolver := New(newSafeTestTransport(t), 0)
+
+	t.Run("name and size from a partial response", func(t *testing.T) {
+		t.Parallel()
+
+		result, err := resolver.Resolve(context.Background(), srv.URL+"/redirect")
+		assert.NoError(t, err)
+		assert.Equal(t, srv.URL+"/download", result.ResolvedURL)
+		assert.Equal(t, "", result.Title)
+		assert.Equal(t, OutcomeFile, result.Outcome)
+		assert.Equal(t, &FileInfo{Name: "report.pdf", MIMEType: "application/pdf", Size: int64(len(content))}, result.File)
+	})
+
+	t.Run("name from path", func(t *testing.T) {
+		t.Parallel()
+
+		result, err := resolver.Resolve(context.Background(), srv.URL+"/files/annual%20report.pdf")
+		assert.NoError(t, err)
+		assert.Equal(t, &FileInfo{Name: "annual report.pdf", MIMEType: "application/pdf", Size: 1024}, result.File)
+	})
+
+	t.Run("html has no file metadata", func(t *testing.T) {
+		t.Parallel()
+
+		htmlSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<title>title</title>")) //nolint:errcheck
+		}))
+		defer htmlSrv.Close()
+
+		result, err := resolver.Resolve(context.Background(), htmlSrv.URL+"/page.html")
+		assert.NoError(t, err)
+		assert.Equal(t, "title", result.Title)
+		assert.Nil(t, result.File)
+	})
+}
+
+func TestFileInfo(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		path   string
+		status int
+		header http.Header
+		length int64
+		want   *FileInfo
+	}{
+		"content-disposition filename": {
+			path:   "/download",
+			status: http.StatusOK,
+			header: http.Header{
+				"Content-Type":        {"application/pdf"},
+				"Content-Disposition": {`attachment; filename="report.pdf"`},
+			},
+			length: 2048,
+			want:   &FileInfo{Name: "report.pdf", MIMEType: "application/pdf", Size: 2048},
+		},
+		"extended filename": {
+			path:   "/download",
+			status: http.StatusOK,
+			header: http.Header{
+				"Content-Type":        {"application/pdf"},
+				"Content-Disposition": {`attachment; filename*=UTF-8''r%C3%A9sum%C3%A9.pdf`},
+			},
+			length: 2048,
+			want:   &FileInfo{Name: "résumé.pdf", MIMEType: "application/pdf", Size: 2048},
+		},
+		"directories stripped from filename": {
+			path:   "/download",
+			status: http.StatusOK,
+			header: http.Header{
+				"Content-Type":        {"application/pdf"},
+				"Content-Disposition": {`attachment; filename="..\\..\\report.pdf"`},
+			},
+			length: 2048,
+			want:   &FileInfo{Name: "report.pdf", MIMEType: "application/pdf", Size: 2048},
+		},
+		"invalid content-disposition falls back to path": {
+			path:   "/files/data.csv",
+			status: http.StatusOK,
+			header: http.Header{
+				"Content-Type":        {"text/csv; charset=utf-8"},
+				"Content-Disposition": {`attachment; filename=`},
+			},
+			length: 10,
+			want:   &FileInfo{Name: "data.csv", MIMEType: "text/csv", Size: 10},
+		},
+		"no name at root": {
+			path:   "/",
+			status: http.StatusOK,
+			header: http.Header{"Content-Type": {"image/png"}},
+			length: -1,
+			want:   &FileInfo{Name: "", MIMEType: "image/png", Size: -1},
+		},
+		"size from content-range": {
+			path:   "/video.mp4",
+			status: http.StatusPartialContent,
+			header: http.Header{
+				"Content-Type":  {"video/mp4"},
+				"Content-Range": {"bytes 0-1023/146515"},
+			},
+			length: 1024,
+			want:   &FileInfo{Name: "video.mp4", MIMEType: "video/mp4", Size: 146515},
+		},
+		"unknown size in content-range": {
+			path:   "/video.mp4",
+			status: http.StatusPartialContent,
+			header: http.Header{
+				"Content-Type":  {"video/mp4"},
+				"Content-Range": {"bytes 0-1023/*"},
+			},
+			length: 1024,
+			want:   &FileInfo{Name: "video.mp4", MIMEType: "video/mp4", Size: -1},
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			resp := &http.Response{
+				StatusCode:    tc.status,
+				Header:        tc.header,
+				ContentLength: tc.length,
+				Request:       &http.Request{URL: &url.URL{Scheme: "https", Host: "example.com", Path: tc.path}},
+			}
+			assert.Equal(t, tc.want, fileInfo(resp))
+		})
+	}
+}
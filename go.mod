@@ -3,17 +3,28 @@ module github.com/mccutchen/urlresolver
 go 1.21
 
 require (
-	github.com/PuerkitoBio/purell v1.2.1
+	github.com/prometheus/client_golang v1.19.1
+	github.com/refraction-networking/utls v1.6.7
 	github.com/stretchr/testify v1.7.0
 	golang.org/x/net v0.33.0
-	golang.org/x/sync v0.10.0
 	golang.org/x/text v0.21.0
+	golang.org/x/time v0.8.0
 )
 
 require (
+	github.com/andybalholm/brotli v1.0.6 // indirect
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/cloudflare/circl v1.3.7 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/kr/pretty v0.2.0 // indirect
+	github.com/klauspost/compress v1.17.4 // indirect
+	github.com/kr/text v0.2.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	gopkg.in/check.v1 v1.0.0-20190902080502-41f04d3bba15 // indirect
+	github.com/prometheus/client_model v0.5.0 // indirect
+	github.com/prometheus/common v0.48.0 // indirect
+	github.com/prometheus/procfs v0.12.0 // indirect
+	golang.org/x/crypto v0.31.0 // indirect
+	golang.org/x/sys v0.28.0 // indirect
+	google.golang.org/protobuf v1.33.0 // indirect
 	gopkg.in/yaml.v3 v3.0.0 // indirect
 )
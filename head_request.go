@@ -0,0 +1,51 @@
+package urlresolver
+
+import (
+	"context"
+	"net/http"
+)
+
+// WithHEADRequests makes the resolver follow redirects using HEAD requests,
+// which avoids downloading response bodies but means no titles are found.
+//
+// Servers that reject HEAD requests are transparently retried with GET (in
+// which case a title may be found after all), and Result.Method reports
+// which method ultimately succeeded.
+func WithHEADRequests() Option {
+	return func(r *Resolver) {
+		r.headRequests = true
+	}
+}
+
+// fetchWithFallback fetches the given URL, using a HEAD request and falling
+// back to GET if the resolver is configured to do so.
+func (r *Resolver) fetchWithFallback(ctx context.Context, givenURL string, result *Result) (*http.Response, error) {
+	if !r.headRequests {
+		return r.fetch(ctx, http.MethodGet, givenURL, result)
+	}
+
+	headResult := *result
+	resp, err := r.fetch(ctx, http.MethodHead, givenURL, &headResult)
+	if err != nil || !rejectsHEAD(resp) {
+		headResult.Method = http.MethodHead
+		*result = headResult
+		return resp, err
+	}
+	resp.Body.Close()
+
+	// Redirects may have been rejected along with the HEAD request itself, so
+	// we start over from the given URL rather than from the rejecting hop.
+	result.Method = http.MethodGet
+	return r.fetch(ctx, http.MethodGet, givenURL, result)
+}
+
+// rejectsHEAD returns true if the given response to a HEAD request indicates
+// that the server does not support HEAD requests.
+func rejectsHEAD(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusForbidden, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return true
+	default:
+		return false
+	}
+}
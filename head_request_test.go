@@ -0,0 +1,98 @@
+package urlresolver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHEADRequests(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		headStatus  int
+		wantMethods []string
+		wantResult  Result
+	}{
+		"head succeeds": {
+			headStatus:  http.StatusOK,
+			wantMethods: []string{"HEAD", "HEAD"},
+			wantResult: Result{
+				ResolvedURL: "/target",
+				Outcome:     OutcomePartialNoTitle,
+				StatusCode:  200,
+				Method:      "HEAD",
+			},
+		},
+		"405 falls back to get": {
+			headStatus:  http.StatusMethodNotAllowed,
+			wantMethods: []string{"HEAD", "HEAD", "GET", "GET"},
+			wantResult: Result{
+				ResolvedURL: "/target",
+				Title:       "title",
+				Outcome:     OutcomeOK,
+				StatusCode:  200,
+				Method:      "GET",
+			},
+		},
+		"403 falls back to get": {
+			headStatus:  http.StatusForbidden,
+			wantMethods: []string{"HEAD", "HEAD", "GET", "GET"},
+			wantResult: Result{
+				ResolvedURL: "/target",
+				Title:       "title",
+				Outcome:     OutcomeOK,
+				StatusCode:  200,
+				Method:      "GET",
+			},
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var methods []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				methods = append(methods, r.Method)
+				if r.URL.Path == "/redirect" {
+					http.Redirect(w, r, "/target", http.StatusFound)
+					return
+				}
+				if r.Method == http.MethodHead {
+					w.WriteHeader(tc.headStatus)
+					return
+				}
+				w.Write([]byte("<title>title</title>")) //nolint:errcheck
+			}))
+			defer srv.Close()
+
+			resolver := New(newSafeTestTransport(t), 0, WithHEADRequests())
+			result, err := resolver.Resolve(context.Background(), srv.URL+"/redirect")
+			assert.NoError(t, err)
+
+			tc.wantResult.ResolvedURL = srv.URL + tc.wantResult.ResolvedURL
+			tc.wantResult.IntermediateURLs = []string{srv.URL + "/redirect"}
+			assert.Equal(t, tc.wantResult, withoutHops(t, result))
+			assert.Equal(t, tc.wantMethods, methods)
+		})
+	}
+
+	t.Run("get by default", func(t *testing.T) {
+		t.Parallel()
+
+		var methods []string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			methods = append(methods, r.Method)
+		}))
+		defer srv.Close()
+
+		result, err := New(newSafeTestTransport(t), 0).Resolve(context.Background(), srv.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, "", result.Method)
+		assert.Equal(t, []string{"GET"}, methods)
+	})
+}
@@ -0,0 +1,155 @@
+package urlresolver
+
+import (
+	"net/url"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/idna"
+)
+
+// SuspiciousHost describes a resolved hostname that may be an IDN homograph
+// of another hostname, e.g. аpple.com with a Cyrillic а. Consumers
+// displaying resolved URLs may want to warn users about such hosts, and
+// show them the ASCII form.
+type SuspiciousHost struct {
+	// Unicode is the hostname in its Unicode form.
+	Unicode string
+	// ASCII is the hostname in its ASCII (punycode) form.
+	ASCII string
+}
+
+// scripts are the writing systems we distinguish between when looking for
+// mixed-script labels. Any letter in none of these is treated as belonging
+// to an unknown script.
+var scripts = map[string]*unicode.RangeTable{
+	"Arabic":     unicode.Arabic,
+	"Armenian":   unicode.Armenian,
+	"Bopomofo":   unicode.Bopomofo,
+	"Cherokee":   unicode.Cherokee,
+	"Cyrillic":   unicode.Cyrillic,
+	"Devanagari": unicode.Devanagari,
+	"Georgian":   unicode.Georgian,
+	"Greek":      unicode.Greek,
+	"Han":        unicode.Han,
+	"Hangul":     unicode.Hangul,
+	"Hebrew":     unicode.Hebrew,
+	"Hiragana":   unicode.Hiragana,
+	"Katakana":   unicode.Katakana,
+	"Latin":      unicode.Latin,
+	"Thai":       unicode.Thai,
+}
+
+// allowedScriptMixes are the combinations of scripts that commonly appear
+// together in legitimate hostnames, per the "highly restrictive" level of
+// Unicode Technical Standard #39.
+var allowedScriptMixes = []map[string]bool{
+	{"Latin": true, "Han": true, "Hiragana": true, "Katakana": true},
+	{"Latin": true, "Han": true, "Bopomofo": true},
+	{"Latin": true, "Han": true, "Hangul": true},
+}
+
+// latinLookalikes are non-Latin lowercase letters that are visually
+// indistinguishable (or nearly so) from Latin letters. A label made up
+// entirely of these can impersonate an all-Latin label.
+var latinLookalikes = map[rune]bool{
+	// Cyrillic
+	'а': true, 'в': true, 'е': true, 'і': true, 'ј': true, 'к': true,
+	'м': true, 'н': true, 'о': true, 'р': true, 'с': true, 'т': true,
+	'у': true, 'х': true, 'ѕ': true, 'ԁ': true, 'ԛ': true, 'ԝ': true,
+	'ӏ': true, 'һ': true, 'ь': true,
+	// Greek
+	'α': true, 'ε': true, 'ι': true, 'κ': true, 'ν': true, 'ο': true,
+	'ρ': true, 'τ': true, 'υ': true, 'χ': true,
+}
+
+// detectSuspiciousHost returns a non-nil SuspiciousHost if the given URL's
+// host contains a label that mixes scripts in an unusual way or that is made
+// up entirely of non-Latin letters that look like Latin letters.
+func detectSuspiciousHost(rawURL string) *SuspiciousHost {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+	unicodeHost, err := idna.Punycode.ToUnicode(strings.ToLower(u.Hostname()))
+	if err != nil {
+		return nil
+	}
+
+	suspicious := false
+	for _, label := range strings.Split(unicodeHost, ".") {
+		if isSuspiciousLabel(label) {
+			suspicious = true
+			break
+		}
+	}
+	if !suspicious {
+		return nil
+	}
+
+	asciiHost, err := idna.Punycode.ToASCII(unicodeHost)
+	if err != nil {
+		return nil
+	}
+	return &SuspiciousHost{
+		Unicode: unicodeHost,
+		ASCII:   asciiHost,
+	}
+}
+
+func isSuspiciousLabel(label string) bool {
+	var (
+		found         = make(map[string]bool)
+		allLookalikes = true
+		hasNonASCII   = false
+	)
+	for _, r := range label {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		if r > unicode.MaxASCII {
+			hasNonASCII = true
+		}
+		found[scriptOf(r)] = true
+		if !latinLookalikes[r] {
+			allLookalikes = false
+		}
+	}
+	if !hasNonASCII {
+		return false
+	}
+
+	// Whole-script confusables, e.g. аррӏе.com written entirely in Cyrillic
+	if len(found) == 1 && !found["Latin"] && allLookalikes {
+		return true
+	}
+
+	// Mixed-script labels, e.g. аpple.com with a single Cyrillic а
+	if len(found) > 1 {
+		for _, allowed := range allowedScriptMixes {
+			if isSubset(found, allowed) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func scriptOf(r rune) string {
+	for name, table := range scripts {
+		if unicode.Is(table, r) {
+			return name
+		}
+	}
+	return "Unknown"
+}
+
+func isSubset(a, b map[string]bool) bool {
+	for k := range a {
+		if !b[k] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,71 @@
+package urlresolver
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectSuspiciousHost(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]*SuspiciousHost{
+		// ascii hosts are never suspicious
+		"https://apple.com/":         nil,
+		"https://www.example.co.uk/": nil,
+		"/relative/path":             nil,
+
+		// legitimate internationalized hosts
+		"https://münchen.de/":        nil,
+		"https://xn--mnchen-3ya.de/": nil,
+		"https://пример.рф/":         nil,
+		"https://例え.テスト/":            nil,
+		"https://google.日本/":         nil,
+		"https://ελληνικά.gr/":       nil,
+		"https://www.한국.com/":        nil,
+		"https://abc中文.com/":         nil,
+
+		// mixed script
+		"https://аpple.com/": {Unicode: "аpple.com", ASCII: "xn--pple-43d.com"},
+		"https://xn--pple-43d.com/foo": {
+			Unicode: "аpple.com",
+			ASCII:   "xn--pple-43d.com",
+		},
+		"https://paypαl.com/": {Unicode: "paypαl.com", ASCII: "xn--paypl-g9d.com"},
+
+		// whole-script confusable
+		"https://аррӏе.com/":            {Unicode: "аррӏе.com", ASCII: "xn--80ak6aa92e.com"},
+		"https://xn--80ak6aa92e.com./x": {Unicode: "аррӏе.com.", ASCII: "xn--80ak6aa92e.com."},
+	}
+	for given, want := range testCases {
+		given, want := given, want
+		t.Run(given, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, want, detectSuspiciousHost(given))
+		})
+	}
+}
+
+func TestSuspiciousHostResult(t *testing.T) {
+	t.Parallel()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<title>Totally Apple</title>`)) //nolint:errcheck
+	})
+	resolver := New(newHandlerTestTransport(t, "xn--pple-43d.com", handler), 0)
+
+	result, err := resolver.Resolve(context.Background(), "http://xn--pple-43d.com/")
+	assert.NoError(t, err)
+	assert.Equal(t, Result{
+		ResolvedURL: "http://xn--pple-43d.com/",
+		Title:       "Totally Apple",
+		Outcome:     OutcomeOK,
+		StatusCode:  200,
+		SuspiciousHost: &SuspiciousHost{
+			Unicode: "аpple.com",
+			ASCII:   "xn--pple-43d.com",
+		},
+	}, withoutHops(t, result))
+}
@@ -0,0 +1,61 @@
+package urlresolver
+
+import (
+	"context"
+	"net/url"
+)
+
+// Hooks are optional callbacks invoked at points in the lifecycle of each
+// resolution, allowing callers to collect custom metrics or apply their own
+// policies. Hooks are called once per resolution, even if the result is
+// shared by coalesced requests.
+type Hooks struct {
+	// OnRedirect is called with each hop that redirected and the URL it
+	// redirected to, before the redirect is followed. Returning an error
+	// aborts resolution with that error.
+	OnRedirect func(ctx context.Context, hop Hop, target *url.URL) error
+
+	// OnUnwrap is called with each hop that was unwrapped and the URL it was
+	// unwrapped to. Returning an error aborts resolution with that error.
+	OnUnwrap func(ctx context.Context, hop Hop, target *url.URL) error
+
+	// OnTitleParsed is called with the resolved URL and its title (which may
+	// be empty) after the final response has been parsed.
+	OnTitleParsed func(ctx context.Context, resolvedURL string, title string)
+
+	// OnError is called with the given URL and the error when resolution
+	// fails.
+	OnError func(ctx context.Context, givenURL string, err error)
+}
+
+// WithHooks sets the resolver's lifecycle hooks.
+//
+// Hooks that abort resolution may return one of this package's errors (e.g.
+// ErrBlockedDomain) to control the resulting Outcome.
+func WithHooks(hooks Hooks) Option {
+	return func(r *Resolver) {
+		r.hooks = hooks
+	}
+}
+
+// onUnwrap calls the OnUnwrap hook, if any.
+func (r *Resolver) onUnwrap(ctx context.Context, hop Hop, target *url.URL) error {
+	if r.hooks.OnUnwrap == nil {
+		return nil
+	}
+	return r.hooks.OnUnwrap(ctx, hop, target)
+}
+
+// onTitleParsed calls the OnTitleParsed hook, if any.
+func (r *Resolver) onTitleParsed(ctx context.Context, resolvedURL string, title string) {
+	if r.hooks.OnTitleParsed != nil {
+		r.hooks.OnTitleParsed(ctx, resolvedURL, title)
+	}
+}
+
+// onError calls the OnError hook, if any.
+func (r *Resolver) onError(ctx context.Context, givenURL string, err error) {
+	if r.hooks.OnError != nil {
+		r.hooks.OnError(ctx, givenURL, err)
+	}
+}
@@ -0,0 +1,128 @@
+package urlresolver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testUnwrapper struct {
+	unwrap func(u *url.URL) (*url.URL, bool)
+}
+
+func (u *testUnwrapper) Unwrap(_ context.Context, given *url.URL) (*url.URL, bool) {
+	return u.unwrap(given)
+}
+
+func TestHooks(t *testing.T) {
+	t.Parallel()
+
+	newServer := func() *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/redirect":
+				http.Redirect(w, r, "/target", http.StatusFound)
+			default:
+				w.Write([]byte("<title>title</title>")) //nolint:errcheck
+			}
+		}))
+	}
+	newUnwrapper := func(srv *httptest.Server) Unwrapper {
+		return &testUnwrapper{func(u *url.URL) (*url.URL, bool) {
+			if u.Host != "wrapped.link" {
+				return nil, false
+			}
+			target, _ := url.Parse(srv.URL + "/redirect")
+			return target, true
+		}}
+	}
+
+	t.Run("hooks called", func(t *testing.T) {
+		t.Parallel()
+
+		srv := newServer()
+		defer srv.Close()
+
+		var events []string
+		hooks := Hooks{
+			OnRedirect: func(_ context.Context, hop Hop, target *url.URL) error {
+				events = append(events, "redirect "+hop.URL+" -> "+target.String())
+				return nil
+			},
+			OnUnwrap: func(_ context.Context, hop Hop, target *url.URL) error {
+				events = append(events, "unwrap "+hop.URL+" -> "+target.String())
+				return nil
+			},
+			OnTitleParsed: func(_ context.Context, resolvedURL string, title string) {
+				events = append(events, "title "+resolvedURL+" "+title)
+			},
+			OnError: func(_ context.Context, givenURL string, err error) {
+				events = append(events, "error "+err.Error())
+			},
+		}
+		resolver := New(newSafeTestTransport(t), 0, WithHooks(hooks), WithUnwrappers(newUnwrapper(srv)))
+
+		_, err := resolver.Resolve(context.Background(), "https://wrapped.link/abc")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{
+			"unwrap https://wrapped.link/abc -> " + srv.URL + "/redirect",
+			"redirect " + srv.URL + "/redirect -> " + srv.URL + "/target",
+			"title " + srv.URL + "/target title",
+		}, events)
+	})
+
+	t.Run("redirect aborted", func(t *testing.T) {
+		t.Parallel()
+
+		srv := newServer()
+		defer srv.Close()
+
+		var gotErr error
+		hooks := Hooks{
+			OnRedirect: func(_ context.Context, _ Hop, target *url.URL) error {
+				return ErrBlockedDomain
+			},
+			OnError: func(_ context.Context, givenURL string, err error) {
+				gotErr = err
+			},
+		}
+		resolver := New(newSafeTestTransport(t), 0, WithHooks(hooks))
+
+		result, err := resolver.Resolve(context.Background(), srv.URL+"/redirect")
+		assert.ErrorIs(t, err, ErrBlockedDomain)
+		assert.Equal(t, err, gotErr)
+		assert.Equal(t, Result{
+			ResolvedURL:      srv.URL + "/target",
+			IntermediateURLs: []string{srv.URL + "/redirect"},
+			Outcome:          OutcomeBlocked,
+		}, withoutHops(t, result))
+	})
+
+	t.Run("unwrap aborted", func(t *testing.T) {
+		t.Parallel()
+
+		srv := newServer()
+		defer srv.Close()
+
+		abort := errors.New("abort")
+		hooks := Hooks{
+			OnUnwrap: func(_ context.Context, _ Hop, target *url.URL) error {
+				return abort
+			},
+		}
+		resolver := New(newSafeTestTransport(t), 0, WithHooks(hooks), WithUnwrappers(newUnwrapper(srv)))
+
+		result, err := resolver.Resolve(context.Background(), "https://wrapped.link/abc")
+		assert.ErrorIs(t, err, abort)
+		assert.Equal(t, Result{
+			ResolvedURL:      srv.URL + "/redirect",
+			IntermediateURLs: []string{"https://wrapped.link/abc"},
+			Outcome:          OutcomeError,
+		}, withoutHops(t, result))
+	})
+}
@@ -0,0 +1,37 @@
+package urlresolver
+
+import "time"
+
+// HopKind describes how the resolver moved on from a hop to the next URL in
+// the chain.
+type HopKind string
+
+// Hop kinds.
+const (
+	// HopRedirect means the hop responded with an HTTP redirect.
+	HopRedirect HopKind = "redirect"
+	// HopUnwrap means the next URL was extracted from the hop's URL itself,
+	// without making a request (e.g. Sailthru tracking links).
+	HopUnwrap HopKind = "unwrap"
+)
+
+// Hop describes one intermediate URL in a redirect chain.
+type Hop struct {
+	URL  string
+	Kind HopKind
+	// StatusCode is the status of the hop's response, or 0 if no request
+	// was made.
+	StatusCode int
+	// Latency is the time it took to receive the hop's response headers, or
+	// 0 if no request was made.
+	Latency time.Duration
+	// Permanent is true if the hop responded with a permanent (301 or 308)
+	// redirect.
+	Permanent bool
+}
+
+// addHop records an intermediate hop in the result.
+func (r *Result) addHop(hop Hop) {
+	r.IntermediateURLs = append(r.IntermediateURLs, hop.URL)
+	r.Hops = append(r.Hops, hop)
+}
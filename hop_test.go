@@ -0,0 +1,63 @@
+package urlresolver
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHops(t *testing.T) {
+	t.Parallel()
+
+	const delay = 20 * time.Millisecond
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusMovedPermanently)
+		case "/b":
+			time.Sleep(delay)
+			http.Redirect(w, r, "/c", http.StatusFound)
+		default:
+			w.Write([]byte("<title>title</title>")) //nolint:errcheck
+		}
+	}))
+	defer srv.Close()
+
+	// a Sailthru tracking link wrapping our first URL is unwrapped without
+	// making a request
+	var (
+		targetURL  = srv.URL + "/a"
+		encodedURL = base64.RawURLEncoding.EncodeToString([]byte(targetURL))
+		givenURL   = fmt.Sprintf("https://link.example.com/click/00000000.0000/%s/0000", encodedURL)
+	)
+
+	resolver := New(newSafeTestTransport(t), 0)
+	result, err := resolver.Resolve(context.Background(), givenURL)
+	assert.NoError(t, err)
+	assert.Equal(t, srv.URL+"/c", result.ResolvedURL)
+
+	if !assert.Len(t, result.Hops, 3) {
+		return
+	}
+
+	assert.Equal(t, Hop{URL: givenURL, Kind: HopUnwrap}, result.Hops[0])
+
+	assert.Equal(t, srv.URL+"/a", result.Hops[1].URL)
+	assert.Equal(t, HopRedirect, result.Hops[1].Kind)
+	assert.Equal(t, http.StatusMovedPermanently, result.Hops[1].StatusCode)
+	assert.True(t, result.Hops[1].Permanent)
+	assert.Greater(t, result.Hops[1].Latency, time.Duration(0))
+
+	assert.Equal(t, srv.URL+"/b", result.Hops[2].URL)
+	assert.Equal(t, HopRedirect, result.Hops[2].Kind)
+	assert.Equal(t, http.StatusFound, result.Hops[2].StatusCode)
+	assert.False(t, result.Hops[2].Permanent)
+	assert.GreaterOrEqual(t, result.Hops[2].Latency, delay)
+}
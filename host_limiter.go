@@ -0,0 +1,157 @@
+package urlresolver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// WithMaxConcurrencyPerHost limits the number of simultaneous outbound
+// requests to any single host, so that a burst of lookups against one domain
+// (e.g. a popular URL shortener) does not open hundreds of connections at
+// once. Requests over the limit wait for a slot until their context is done.
+//
+// A request holds its slot until its response body is closed. If n is zero
+// or negative, concurrency is not limited.
+func WithMaxConcurrencyPerHost(n int) Option {
+	return func(r *Resolver) {
+		if n <= 0 {
+			r.hostLimiter = nil
+			return
+		}
+		r.hostLimiter = newHostLimiter(n)
+	}
+}
+
+// InFlightByHost returns the number of outbound requests currently in flight
+// to each host, along with the number of requests waiting for a slot. It is
+// only populated if WithMaxConcurrencyPerHost is used.
+func (r *Resolver) InFlightByHost() map[string]HostConcurrency {
+	if r.hostLimiter == nil {
+		return nil
+	}
+	return r.hostLimiter.stats()
+}
+
+// HostConcurrency reports the outbound concurrency for a single host.
+type HostConcurrency struct {
+	InFlight int
+	Waiting  int
+}
+
+// hostLimiter manages a semaphore per host.
+type hostLimiter struct {
+	limit int
+
+	mu    sync.Mutex
+	hosts map[string]*hostSemaphore
+}
+
+type hostSemaphore struct {
+	slots   chan struct{}
+	waiting int
+}
+
+func newHostLimiter(limit int) *hostLimiter {
+	return &hostLimiter{
+		limit: limit,
+		hosts: make(map[string]*hostSemaphore),
+	}
+}
+
+// acquire waits for a slot for the given host, returning a function that
+// must be called to release it.
+func (l *hostLimiter) acquire(ctx context.Context, host string) (func(), error) {
+	host = strings.ToLower(host)
+
+	l.mu.Lock()
+	sem, ok := l.hosts[host]
+	if !ok {
+		sem = &hostSemaphore{slots: make(chan struct{}, l.limit)}
+		l.hosts[host] = sem
+	}
+	sem.waiting++
+	l.mu.Unlock()
+
+	var err error
+	select {
+	case sem.slots <- struct{}{}:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	l.mu.Lock()
+	sem.waiting--
+	if err != nil {
+		l.maybeRemove(host, sem)
+	}
+	l.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			<-sem.slots
+			l.mu.Lock()
+			l.maybeRemove(host, sem)
+			l.mu.Unlock()
+		})
+	}, nil
+}
+
+// maybeRemove removes an idle semaphore, to avoid accumulating one per host
+// ever seen. Must be called with the lock held.
+func (l *hostLimiter) maybeRemove(host string, sem *hostSemaphore) {
+	if sem.waiting == 0 && len(sem.slots) == 0 {
+		delete(l.hosts, host)
+	}
+}
+
+func (l *hostLimiter) stats() map[string]HostConcurrency {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	stats := make(map[string]HostConcurrency, len(l.hosts))
+	for host, sem := range l.hosts {
+		stats[host] = HostConcurrency{
+			InFlight: len(sem.slots),
+			Waiting:  sem.waiting,
+		}
+	}
+	return stats
+}
+
+// hostLimitTransport is an http.RoundTripper that enforces per-host
+// concurrency limits.
+type hostLimitTransport struct {
+	transport http.RoundTripper
+	limiter   *hostLimiter
+}
+
+func (t *hostLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	release, err := t.limiter.acquire(req.Context(), req.URL.Host)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releasingBody{resp.Body, release}
+	return resp, nil
+}
+
+// releasingBody releases a host limiter slot when the body is closed.
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	defer b.release()
+	return b.ReadCloser.Close()
+}
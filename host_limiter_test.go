@@ -0,0 +1,132 @@
+package urlresolver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHostLimiter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("limits concurrency per host", func(t *testing.T) {
+		t.Parallel()
+
+		l := newHostLimiter(1)
+		releaseA, err := l.acquire(context.Background(), "a.example.com")
+		assert.NoError(t, err)
+
+		// a different host is unaffected
+		releaseB, err := l.acquire(context.Background(), "b.example.com")
+		assert.NoError(t, err)
+
+		// the same host (in any case) must wait
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err = l.acquire(ctx, "A.EXAMPLE.COM")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		assert.Equal(t, map[string]HostConcurrency{
+			"a.example.com": {InFlight: 1},
+			"b.example.com": {InFlight: 1},
+		}, l.stats())
+
+		// releasing is idempotent and frees the slot
+		releaseA()
+		releaseA()
+		releaseA, err = l.acquire(context.Background(), "a.example.com")
+		assert.NoError(t, err)
+
+		releaseA()
+		releaseB()
+		assert.Equal(t, map[string]HostConcurrency{}, l.stats(), "idle hosts should be removed")
+	})
+
+	t.Run("waiting requests are reported", func(t *testing.T) {
+		t.Parallel()
+
+		l := newHostLimiter(1)
+		release, err := l.acquire(context.Background(), "example.com")
+		assert.NoError(t, err)
+
+		acquired := make(chan struct{})
+		go func() {
+			release, err := l.acquire(context.Background(), "example.com")
+			assert.NoError(t, err)
+			release()
+			close(acquired)
+		}()
+
+		assert.Eventually(t, func() bool {
+			return l.stats()["example.com"] == HostConcurrency{InFlight: 1, Waiting: 1}
+		}, time.Second, time.Millisecond)
+
+		release()
+		<-acquired
+	})
+}
+
+func TestMaxConcurrencyPerHost(t *testing.T) {
+	t.Parallel()
+
+	var (
+		inFlight    int64
+		maxInFlight int64
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(&inFlight, 1)
+		defer atomic.AddInt64(&inFlight, -1)
+		for {
+			cur := atomic.LoadInt64(&maxInFlight)
+			if n <= cur || atomic.CompareAndSwapInt64(&maxInFlight, cur, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte(`<title>title</title>`)) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	resolver := New(newSafeTestTransport(t), 0, WithMaxConcurrencyPerHost(2))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// unique URLs, to avoid coalescing
+			result, err := resolver.Resolve(context.Background(), fmt.Sprintf("%s/%d", srv.URL, i))
+			assert.NoError(t, err)
+			assert.Equal(t, "title", result.Title)
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(2), atomic.LoadInt64(&maxInFlight))
+	assert.Empty(t, resolver.InFlightByHost())
+}
+
+func TestMaxConcurrencyPerHostUnlimited(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<title>title</title>`)) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	for _, n := range []int{0, -1} {
+		resolver := New(newSafeTestTransport(t), time.Second, WithMaxConcurrencyPerHost(n))
+		result, err := resolver.Resolve(context.Background(), srv.URL)
+		assert.NoError(t, err, "n=%d", n)
+		assert.Equal(t, OutcomeOK, result.Outcome, "n=%d", n)
+		assert.Equal(t, "title", result.Title, "n=%d", n)
+		assert.Nil(t, resolver.InFlightByHost(), "n=%d", n)
+	}
+}
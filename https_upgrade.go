@@ -0,0 +1,89 @@
+package urlresolver
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// WithHTTPSUpgrade makes the resolver try https:// first when given an
+// http:// URL, falling back to the original URL if the host cannot be reached
+// over https (e.g. because it does not support TLS or has an invalid
+// certificate). Any other failure, such as a blocked redirect, is returned
+// without falling back. Result.UpgradedToHTTPS reports whether the upgrade
+// succeeded.
+//
+// Note that a failed https attempt counts against the resolver's timeout.
+func WithHTTPSUpgrade() Option {
+	return func(r *Resolver) {
+		r.httpsUpgrade = true
+	}
+}
+
+// fetchWithUpgrade fetches the given URL, first trying to upgrade it to
+// https if the resolver is configured to do so.
+func (r *Resolver) fetchWithUpgrade(ctx context.Context, givenURL string, result *Result) (*http.Response, error) {
+	if r.httpsUpgrade && strings.HasPrefix(strings.ToLower(givenURL), "http://") {
+		upgraded := *result
+		resp, err := r.fetchWithFallback(ctx, "https://"+givenURL[len("http://"):], &upgraded)
+		if err == nil {
+			upgraded.UpgradedToHTTPS = true
+			*result = upgraded
+			return resp, nil
+		}
+		// no time left to fall back, or the failure would not be fixed by
+		// falling back
+		if ctx.Err() != nil || !isHTTPSFailure(err) {
+			*result = upgraded
+			return resp, err
+		}
+	}
+	return r.fetchWithFallback(ctx, givenURL, result)
+}
+
+// isHTTPSFailure returns true if err indicates that a host could not be
+// reached over https at all, as opposed to a failure that would recur over
+// http.
+func isHTTPSFailure(err error) bool {
+	if errors.Is(err, ErrRedirectToPrivateNetwork) {
+		return false
+	}
+	// net/http replaces the tls.RecordHeaderError for a plaintext HTTP
+	// response with an unwrappable error of its own
+	if strings.Contains(err.Error(), "server gave HTTP response to HTTPS client") {
+		return true
+	}
+	var (
+		recordErr    tls.RecordHeaderError
+		verifyErr    *tls.CertificateVerificationError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+		opErr        *net.OpError
+	)
+	switch {
+	case errors.As(err, &recordErr),
+		errors.As(err, &verifyErr),
+		errors.As(err, &authorityErr),
+		errors.As(err, &hostnameErr),
+		errors.As(err, &invalidErr):
+		return true
+	case errors.As(err, &opErr):
+		// "remote error" is a TLS alert sent by the server during the
+		// handshake
+		return opErr.Op == "dial" || opErr.Op == "remote error"
+	default:
+		return false
+	}
+}
+
+// sentHSTS returns true if the given response is from a host that enforces
+// HTTPS via the Strict-Transport-Security header, which is only meaningful
+// over https.
+func sentHSTS(resp *http.Response) bool {
+	return resp.Request.URL.Scheme == "https" && resp.Header.Get("Strict-Transport-Security") != ""
+}
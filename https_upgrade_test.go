@@ -0,0 +1,129 @@
+package urlresolver
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPSUpgrade(t *testing.T) {
+	t.Parallel()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil {
+			w.Header().Set("Strict-Transport-Security", "max-age=63072000")
+		}
+		w.Write([]byte("<title>title</title>")) //nolint:errcheck
+	})
+
+	t.Run("upgraded when https is supported", func(t *testing.T) {
+		t.Parallel()
+
+		srv := httptest.NewTLSServer(handler)
+		defer srv.Close()
+
+		httpURL := "http://" + strings.TrimPrefix(srv.URL, "https://") + "/foo"
+		resolver := New(srv.Client().Transport, 0, WithHTTPSUpgrade())
+		result, err := resolver.Resolve(context.Background(), httpURL)
+		assert.NoError(t, err)
+		assert.Equal(t, Result{
+			ResolvedURL:     srv.URL + "/foo",
+			Title:           "title",
+			Outcome:         OutcomeOK,
+			StatusCode:      200,
+			UpgradedToHTTPS: true,
+			HSTS:            true,
+		}, withoutHops(t, result))
+	})
+
+	t.Run("falls back to http", func(t *testing.T) {
+		t.Parallel()
+
+		srv := httptest.NewServer(handler)
+		defer srv.Close()
+
+		resolver := New(newSafeTestTransport(t), 0, WithHTTPSUpgrade())
+		result, err := resolver.Resolve(context.Background(), srv.URL+"/foo")
+		assert.NoError(t, err)
+		assert.Equal(t, Result{
+			ResolvedURL: srv.URL + "/foo",
+			Title:       "title",
+			Outcome:     OutcomeOK,
+			StatusCode:  200,
+		}, withoutHops(t, result))
+	})
+
+	t.Run("falls back to http when https connection refused", func(t *testing.T) {
+		t.Parallel()
+
+		srv := httptest.NewServer(handler)
+		defer srv.Close()
+
+		var schemes []string
+		transport := &testTransport{
+			roundTrip: func(r *http.Request) (*http.Response, error) {
+				schemes = append(schemes, r.URL.Scheme)
+				if r.URL.Scheme == "https" {
+					return nil, &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+				}
+				return http.DefaultTransport.RoundTrip(r)
+			},
+		}
+		resolver := New(transport, 0, WithHTTPSUpgrade())
+		result, err := resolver.Resolve(context.Background(), srv.URL+"/foo")
+		assert.NoError(t, err)
+		assert.Equal(t, "title", result.Title)
+		assert.False(t, result.UpgradedToHTTPS)
+		assert.Equal(t, []string{"https", "http"}, schemes)
+	})
+
+	t.Run("policy errors do not fall back", func(t *testing.T) {
+		t.Parallel()
+
+		srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "https://blocked.example.com/", http.StatusFound)
+		}))
+		defer srv.Close()
+
+		var schemes []string
+		transport := &testTransport{
+			roundTrip: func(r *http.Request) (*http.Response, error) {
+				schemes = append(schemes, r.URL.Scheme)
+				return srv.Client().Transport.RoundTrip(r)
+			},
+		}
+		httpURL := "http://" + strings.TrimPrefix(srv.URL, "https://") + "/foo"
+		resolver := New(transport, 0, WithHTTPSUpgrade(), WithDomainBlocklist("blocked.example.com"))
+		result, err := resolver.Resolve(context.Background(), httpURL)
+		assert.ErrorIs(t, err, ErrBlockedDomain)
+		assert.Equal(t, OutcomeBlocked, result.Outcome)
+		assert.Equal(t, []string{"https"}, schemes)
+	})
+
+	t.Run("not upgraded by default", func(t *testing.T) {
+		t.Parallel()
+
+		srv := httptest.NewServer(handler)
+		defer srv.Close()
+
+		var schemes []string
+		transport := &testTransport{
+			roundTrip: func(r *http.Request) (*http.Response, error) {
+				schemes = append(schemes, r.URL.Scheme)
+				return http.DefaultTransport.RoundTrip(r)
+			},
+		}
+		resolver := New(transport, 0)
+		result, err := resolver.Resolve(context.Background(), srv.URL+"/foo")
+		assert.NoError(t, err)
+		assert.False(t, result.UpgradedToHTTPS)
+		assert.False(t, result.HSTS)
+		assert.Equal(t, []string{"http"}, schemes)
+	})
+}
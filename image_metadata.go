@@ -0,0 +1,188 @@
+package urlresolver
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf16"
+
+	// Register the image formats whose headers we can decode.
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// maxImageHeaderSize is the most we'll read from an image to find its format,
+// dimensions, and EXIF metadata. JPEG EXIF segments are limited to 64kb, and
+// the dimensions are almost always found shortly after them.
+const maxImageHeaderSize = 80 * 1024
+
+// ImageInfo describes an image found at the end of resolution.
+type ImageInfo struct {
+	// Format is the image format, e.g. "gif", "jpeg", or "png".
+	Format string
+	Width  int
+	Height int
+
+	// Title and Description are taken from a JPEG's EXIF metadata, if
+	// present.
+	Title       string
+	Description string
+}
+
+// imageInfo decodes just enough of the given image response to describe it,
+// returning nil if the image's format is unsupported or its header can't be
+// read. Image metadata is a nicety, so errors here do not fail resolution.
+func (r *Resolver) imageInfo(resp *http.Response) *ImageInfo {
+	buf := r.pool.Get()
+	defer r.pool.Put(buf)
+
+	body, err := decompressBody(resp)
+	if err != nil {
+		return nil
+	}
+	defer body.Close()
+
+	_, err = buf.ReadFrom(io.LimitReader(body, int64(min(maxImageHeaderSize, r.maxBodySize))))
+	r.metrics.bodyRead(buf.Len())
+	if err != nil {
+		return nil
+	}
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		return nil
+	}
+	info := &ImageInfo{
+		Format: format,
+		Width:  config.Width,
+		Height: config.Height,
+	}
+	if format == "jpeg" {
+		info.Title, info.Description = parseEXIF(jpegEXIF(buf.Bytes()))
+	}
+	return info
+}
+
+// isImage returns true if the given MIME type is an image type.
+func isImage(mimeType string) bool {
+	return strings.HasPrefix(mimeType, "image/")
+}
+
+// jpegEXIF returns the TIFF-formatted EXIF data embedded in the given JPEG,
+// if any.
+func jpegEXIF(data []byte) []byte {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return nil
+		}
+		marker := data[i+1]
+		if marker == 0xFF {
+			// fill byte
+			i++
+			continue
+		}
+		// Metadata segments precede the start of scan
+		if marker == 0xDA || marker == 0xD9 {
+			return nil
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return nil
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return segment[6:]
+		}
+		i += 2 + length
+	}
+	return nil
+}
+
+// EXIF tags and types we know how to read.
+const (
+	exifTagImageDescription = 0x010E
+	exifTagXPTitle          = 0x9C9B
+
+	exifTypeByte  = 1
+	exifTypeASCII = 2
+)
+
+// parseEXIF returns the title and description found in the first IFD of the
+// given TIFF-formatted EXIF data.
+func parseEXIF(data []byte) (title string, description string) {
+	if len(data) < 8 {
+		return "", ""
+	}
+	var order binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return "", ""
+	}
+	if order.Uint16(data[2:]) != 42 {
+		return "", ""
+	}
+
+	ifd := int(order.Uint32(data[4:]))
+	if ifd < 8 || ifd+2 > len(data) {
+		return "", ""
+	}
+	count := int(order.Uint16(data[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(data) {
+			break
+		}
+		tag := order.Uint16(data[entry:])
+		typ := order.Uint16(data[entry+2:])
+		switch {
+		case tag == exifTagImageDescription && typ == exifTypeASCII:
+			if value := exifValue(data, order, entry); value != nil {
+				description = strings.TrimSpace(string(bytes.TrimRight(value, "\x00")))
+			}
+		case tag == exifTagXPTitle && typ == exifTypeByte:
+			if value := exifValue(data, order, entry); value != nil {
+				title = strings.TrimSpace(decodeUTF16LE(value))
+			}
+		}
+	}
+	return title, description
+}
+
+// exifValue returns the value of the single-byte-typed IFD entry at the given
+// offset, which is stored inline if it fits in 4 bytes.
+func exifValue(data []byte, order binary.ByteOrder, entry int) []byte {
+	n := int(order.Uint32(data[entry+4:]))
+	if n <= 4 {
+		return data[entry+8 : entry+8+n]
+	}
+	offset := int(order.Uint32(data[entry+8:]))
+	if offset < 0 || n > len(data) || offset > len(data)-n {
+		return nil
+	}
+	return data[offset : offset+n]
+}
+
+// decodeUTF16LE decodes the given null-terminated UTF-16LE string, as used by
+// the Windows XP* EXIF tags.
+func decodeUTF16LE(b []byte) string {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		u := binary.LittleEndian.Uint16(b[i:])
+		if u == 0 {
+			break
+		}
+		units = append(units, u)
+	}
+	return string(utf16.Decode(units))
+}
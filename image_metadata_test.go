@@ -0,0 +1,217 @@
+package urlresolver
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageMetadata(t *testing.T) {
+	t.Parallel()
+
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+
+	var pngBuf, gifBuf, jpegBuf bytes.Buffer
+	assert.NoError(t, png.Encode(&pngBuf, img))
+	assert.NoError(t, gif.Encode(&gifBuf, img, nil))
+	assert.NoError(t, jpeg.Encode(&jpegBuf, img, nil))
+	exifJPEG := withEXIF(jpegBuf.Bytes(), testEXIF(binary.LittleEndian, "A title", "A longer description"))
+
+	testCases := map[string]struct {
+		contentType string
+		body        []byte
+		want        *ImageInfo
+	}{
+		"png": {
+			contentType: "image/png",
+			body:        pngBuf.Bytes(),
+			want:        &ImageInfo{Format: "png", Width: 64, Height: 48},
+		},
+		"gif": {
+			contentType: "image/gif",
+			body:        gifBuf.Bytes(),
+			want:        &ImageInfo{Format: "gif", Width: 64, Height: 48},
+		},
+		"jpeg without exif": {
+			contentType: "image/jpeg",
+			body:        jpegBuf.Bytes(),
+			want:        &ImageInfo{Format: "jpeg", Width: 64, Height: 48},
+		},
+		"jpeg with exif": {
+			contentType: "image/jpeg",
+			body:        exifJPEG,
+			want: &ImageInfo{
+				Format:      "jpeg",
+				Width:       64,
+				Height:      48,
+				Title:       "A title",
+				Description: "A longer description",
+			},
+		},
+		"format detected regardless of content type": {
+			contentType: "image/jpeg",
+			body:        pngBuf.Bytes(),
+			want:        &ImageInfo{Format: "png", Width: 64, Height: 48},
+		},
+		"unsupported format": {
+			contentType: "image/webp",
+			body:        []byte("RIFF\x00\x00\x00\x00WEBPVP8 "),
+			want:        nil,
+		},
+		"truncated header": {
+			contentType: "image/png",
+			body:        pngBuf.Bytes()[:10],
+			want:        nil,
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tc.contentType)
+				w.Write(tc.body) //nolint:errcheck
+			}))
+			defer srv.Close()
+
+			resolver := New(newSafeTestTransport(t), 0)
+			result, err := resolver.Resolve(context.Background(), srv.URL+"/image")
+			assert.NoError(t, err)
+			if assert.NotNil(t, result.File) {
+				assert.Equal(t, tc.contentType, result.File.MIMEType)
+				assert.Equal(t, tc.want, result.File.Image)
+			}
+		})
+	}
+
+	t.Run("non-image files are not decoded", func(t *testing.T) {
+		t.Parallel()
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(pngBuf.Bytes()) //nolint:errcheck
+		}))
+		defer srv.Close()
+
+		resolver := New(newSafeTestTransport(t), 0)
+		result, err := resolver.Resolve(context.Background(), srv.URL+"/image.png")
+		assert.NoError(t, err)
+		if assert.NotNil(t, result.File) {
+			assert.Nil(t, result.File.Image)
+		}
+	})
+}
+
+func TestParseEXIF(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		data            []byte
+		wantTitle       string
+		wantDescription string
+	}{
+		"little endian": {
+			data:            testEXIF(binary.LittleEndian, "Title", "Description"),
+			wantTitle:       "Title",
+			wantDescription: "Description",
+		},
+		"big endian": {
+			data:            testEXIF(binary.BigEndian, "Títle", "Déscription"),
+			wantTitle:       "Títle",
+			wantDescription: "Déscription",
+		},
+		"short inline description": {
+			data:            testEXIF(binary.LittleEndian, "", "abc"),
+			wantDescription: "abc",
+		},
+		"empty": {
+			data: nil,
+		},
+		"bad byte order": {
+			data: []byte("XX\x2a\x00\x08\x00\x00\x00"),
+		},
+		"truncated": {
+			data: testEXIF(binary.LittleEndian, "Title", "Description")[:20],
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			title, description := parseEXIF(tc.data)
+			assert.Equal(t, tc.wantTitle, title)
+			assert.Equal(t, tc.wantDescription, description)
+		})
+	}
+}
+
+// testEXIF builds TIFF-formatted EXIF data with the given title and
+// description in its first IFD.
+func testEXIF(order binary.AppendByteOrder, title, description string) []byte {
+	type entry struct {
+		tag, typ uint16
+		value    []byte
+	}
+	var entries []entry
+	if description != "" {
+		entries = append(entries, entry{exifTagImageDescription, exifTypeASCII, append([]byte(description), 0)})
+	}
+	if title != "" {
+		var value []byte
+		for _, u := range append(utf16.Encode([]rune(title)), 0) {
+			value = binary.LittleEndian.AppendUint16(value, u)
+		}
+		entries = append(entries, entry{exifTagXPTitle, exifTypeByte, value})
+	}
+
+	data := []byte("MM")
+	if order == binary.LittleEndian {
+		data = []byte("II")
+	}
+	data = order.AppendUint16(data, 42)
+	data = order.AppendUint32(data, 8)
+	data = order.AppendUint16(data, uint16(len(entries)))
+
+	// values that don't fit inline follow the IFD and its next IFD offset
+	valueOffset := len(data) + len(entries)*12 + 4
+	var values []byte
+	for _, e := range entries {
+		data = order.AppendUint16(data, e.tag)
+		data = order.AppendUint16(data, e.typ)
+		data = order.AppendUint32(data, uint32(len(e.value)))
+		if len(e.value) <= 4 {
+			inline := make([]byte, 4)
+			copy(inline, e.value)
+			data = append(data, inline...)
+			continue
+		}
+		data = order.AppendUint32(data, uint32(valueOffset+len(values)))
+		values = append(values, e.value...)
+	}
+	data = order.AppendUint32(data, 0)
+	return append(data, values...)
+}
+
+// withEXIF inserts an APP1 segment holding the given EXIF data into the given
+// JPEG, just after its start of image marker.
+func withEXIF(jpegData, exif []byte) []byte {
+	payload := append([]byte("Exif\x00\x00"), exif...)
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	result := append([]byte{}, jpegData[:2]...)
+	result = append(result, segment...)
+	return append(result, jpegData[2:]...)
+}
@@ -0,0 +1,57 @@
+package urlutil
+
+import (
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// ASCIIAuthority converts an internationalized hostname in the given URL
+// authority (i.e. [userinfo@]host[:port]) to punycode.
+func ASCIIAuthority(authority string) string {
+	userinfo, hostport := "", authority
+	if idx := strings.LastIndex(authority, "@"); idx >= 0 {
+		userinfo, hostport = authority[:idx+1], authority[idx+1:]
+	}
+	host, port := hostport, ""
+	if idx := strings.LastIndex(hostport, ":"); idx >= 0 && !strings.HasPrefix(hostport, "[") {
+		host, port = hostport[:idx], hostport[idx:]
+	}
+	if !IsASCII(host) {
+		if asciiHost, err := idna.Lookup.ToASCII(host); err == nil {
+			host = asciiHost
+		}
+	}
+	return userinfo + host + port
+}
+
+// IsASCII returns true if s contains only ASCII characters.
+func IsASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchDomains returns true if host is equal to or a subdomain of any of the
+// given domains, which must already be normalized by NormalizeDomains.
+func MatchDomains(host string, domains []string) bool {
+	for _, domain := range domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeDomains lowercases the given domains and strips any trailing dots,
+// for use with MatchDomains.
+func NormalizeDomains(domains []string) []string {
+	normalized := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		normalized = append(normalized, strings.TrimSuffix(strings.ToLower(domain), "."))
+	}
+	return normalized
+}
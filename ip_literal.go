@@ -0,0 +1,62 @@
+package urlresolver
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+)
+
+// IPLiteralPolicy determines how a Resolver handles URLs whose host is an IP
+// literal (e.g. http://203.0.113.7/ or http://[2001:db8::1]/) rather than a
+// domain name. Such URLs are disproportionately used for abuse.
+type IPLiteralPolicy int
+
+// Supported IP literal policies.
+const (
+	// IPLiteralAllow treats IP literal hosts like any other host. This is the
+	// default.
+	IPLiteralAllow IPLiteralPolicy = iota
+
+	// IPLiteralBlock refuses to resolve any URL whose host, or the host of
+	// any redirect target, is an IP literal.
+	IPLiteralBlock
+
+	// IPLiteralNoTitle follows redirects through IP literal hosts, but does
+	// not attempt to extract a title if the final URL has an IP literal
+	// host.
+	IPLiteralNoTitle
+)
+
+// ErrIPLiteralBlocked is returned when a URL is not resolved because its host
+// is an IP literal and the resolver's policy is IPLiteralBlock.
+var ErrIPLiteralBlocked = errors.New("ip literal host blocked")
+
+// WithIPLiteralPolicy sets the policy for URLs with IP literal hosts.
+func WithIPLiteralPolicy(policy IPLiteralPolicy) Option {
+	return func(r *Resolver) {
+		r.ipLiteralPolicy = policy
+	}
+}
+
+// Hosts made up entirely of numeric (decimal, octal, or hex) parts are not
+// valid domain names, but many resolvers will happily interpret them as IPv4
+// addresses (e.g. http://2130706433/ or http://0x7f.1/), so we treat them as
+// IP literals too.
+var numericHostRegex = regexp.MustCompile(`(?i)^(0x[0-9a-f]*|[0-9]+)(\.(0x[0-9a-f]*|[0-9]+))*\.?$`)
+
+// isIPLiteral returns true if the given URL's host is an IP literal.
+func isIPLiteral(u *url.URL) bool {
+	host := u.Hostname()
+	return net.ParseIP(host) != nil || numericHostRegex.MatchString(host)
+}
+
+// checkIPLiteral returns an error if the given URL is disallowed by the
+// policy.
+func checkIPLiteral(policy IPLiteralPolicy, u *url.URL) error {
+	if policy == IPLiteralBlock && isIPLiteral(u) {
+		return fmt.Errorf("%w: %s", ErrIPLiteralBlocked, u.Host)
+	}
+	return nil
+}
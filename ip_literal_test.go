@@ -0,0 +1,125 @@
+package urlresolver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsIPLiteral(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]bool{
+		"http://example.com/":          false,
+		"http://1password.com/":        false,
+		"http://example.123/":          false,
+		"http://203.0.113.7/":          true,
+		"http://203.0.113.7:8080/foo":  true,
+		"http://[2001:db8::1]/":        true,
+		"http://[2001:db8::1]:443/foo": true,
+		"http://2130706433/":           true,
+		"http://0x7f.1/":               true,
+		"http://0177.0.0.1/":           true,
+		"http://127.1./":               true,
+	}
+	for given, want := range testCases {
+		given, want := given, want
+		t.Run(given, func(t *testing.T) {
+			t.Parallel()
+			u, err := url.Parse(given)
+			assert.NoError(t, err)
+			assert.Equal(t, want, isIPLiteral(u))
+		})
+	}
+}
+
+func TestIPLiteralPolicy(t *testing.T) {
+	t.Parallel()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect-to-ip":
+			http.Redirect(w, r, "http://203.0.113.7/", http.StatusFound)
+		default:
+			w.Write([]byte(`<title>title</title>`)) //nolint:errcheck
+		}
+	})
+
+	testCases := map[string]struct {
+		policy     IPLiteralPolicy
+		givenPath  string
+		wantResult Result
+		wantErr    error
+	}{
+		"allow": {
+			policy:    IPLiteralAllow,
+			givenPath: "/foo",
+			wantResult: Result{
+				ResolvedURL: "/foo",
+				Title:       "title",
+				Outcome:     OutcomeOK,
+				StatusCode:  200,
+			},
+		},
+		"block given URL": {
+			policy:    IPLiteralBlock,
+			givenPath: "/foo",
+			wantResult: Result{
+				ResolvedURL: "/foo",
+				Outcome:     OutcomeBlocked,
+			},
+			wantErr: ErrIPLiteralBlocked,
+		},
+		"no title": {
+			policy:    IPLiteralNoTitle,
+			givenPath: "/foo",
+			wantResult: Result{
+				ResolvedURL: "/foo",
+				Outcome:     OutcomePartialNoTitle,
+				StatusCode:  200,
+			},
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewServer(handler)
+			defer srv.Close()
+
+			resolver := New(newSafeTestTransport(t), 0, WithIPLiteralPolicy(tc.policy))
+			result, err := resolver.Resolve(context.Background(), srv.URL+tc.givenPath)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			tc.wantResult.ResolvedURL = renderURL(srv.URL, tc.wantResult.ResolvedURL)
+			assert.Equal(t, tc.wantResult, withoutHops(t, result))
+		})
+	}
+
+	t.Run("block redirect target", func(t *testing.T) {
+		t.Parallel()
+
+		// The test server itself has an IP literal host, so we avoid making
+		// any requests to it by serving example.com via a custom transport.
+		transport := newHandlerTestTransport(t, "example.com", handler)
+
+		resolver := New(transport, 0, WithIPLiteralPolicy(IPLiteralBlock))
+		result, err := resolver.Resolve(context.Background(), "http://example.com/redirect-to-ip")
+		assert.True(t, errors.Is(err, ErrIPLiteralBlocked), "expected ErrIPLiteralBlocked, got %v", err)
+		assert.Equal(t, Result{
+			ResolvedURL:      "http://203.0.113.7/",
+			IntermediateURLs: []string{"http://example.com/redirect-to-ip"},
+			Outcome:          OutcomeBlocked,
+		}, withoutHops(t, result))
+	})
+}
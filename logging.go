@@ -0,0 +1,58 @@
+package urlresolver
+
+import (
+	"context"
+	"log/slog"
+)
+
+// WithLogger makes the resolver log events for each resolution to the given
+// logger: the given URL, each hop followed, any interstitial detected, and
+// the final outcome. Per-hop events are logged at debug level.
+func WithLogger(logger *slog.Logger) Option {
+	return func(r *Resolver) {
+		r.logger = logger
+	}
+}
+
+// logResolution logs the events of a completed resolution.
+func (r *Resolver) logResolution(ctx context.Context, givenURL string, result Result, err error) {
+	if r.logger == nil {
+		return
+	}
+	for i, hop := range result.Hops {
+		attrs := []slog.Attr{
+			slog.String("given_url", givenURL),
+			slog.Int("hop", i),
+			slog.String("url", hop.URL),
+			slog.String("kind", string(hop.Kind)),
+		}
+		if hop.Kind == HopRedirect {
+			attrs = append(attrs,
+				slog.Int("status", hop.StatusCode),
+				slog.Duration("latency", hop.Latency),
+				slog.Bool("permanent", hop.Permanent),
+			)
+		}
+		r.logger.LogAttrs(ctx, slog.LevelDebug, "urlresolver: hop", attrs...)
+	}
+	if result.Interstitial != "" {
+		r.logger.LogAttrs(ctx, slog.LevelInfo, "urlresolver: interstitial detected",
+			slog.String("given_url", givenURL),
+			slog.String("resolved_url", result.ResolvedURL),
+			slog.String("fingerprint", result.Interstitial),
+		)
+	}
+
+	level := slog.LevelInfo
+	attrs := []slog.Attr{
+		slog.String("given_url", givenURL),
+		slog.String("resolved_url", result.ResolvedURL),
+		slog.String("outcome", string(result.Outcome)),
+		slog.Int("hops", len(result.Hops)),
+	}
+	if err != nil {
+		level = slog.LevelWarn
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	r.logger.LogAttrs(ctx, level, "urlresolver: resolved", attrs...)
+}
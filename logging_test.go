@@ -0,0 +1,90 @@
+package urlresolver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger(t *testing.T) {
+	t.Parallel()
+
+	logRecords := func(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+		t.Helper()
+		var records []map[string]interface{}
+		dec := json.NewDecoder(buf)
+		for dec.More() {
+			var record map[string]interface{}
+			assert.NoError(t, dec.Decode(&record))
+			delete(record, "time")
+			delete(record, "latency")
+			records = append(records, record)
+		}
+		return records
+	}
+
+	t.Run("successful resolution", func(t *testing.T) {
+		t.Parallel()
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/redirect":
+				http.Redirect(w, r, "/target", http.StatusMovedPermanently)
+			case "/target":
+				w.Write([]byte("<title>title</title>")) //nolint:errcheck
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer srv.Close()
+
+		buf := &bytes.Buffer{}
+		logger := slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		resolver := New(newSafeTestTransport(t), 0, WithLogger(logger))
+
+		_, err := resolver.Resolve(context.Background(), srv.URL+"/redirect")
+		assert.NoError(t, err)
+		assert.Equal(t, []map[string]interface{}{
+			{
+				"level":     "DEBUG",
+				"msg":       "urlresolver: hop",
+				"given_url": srv.URL + "/redirect",
+				"hop":       float64(0),
+				"url":       srv.URL + "/redirect",
+				"kind":      "redirect",
+				"status":    float64(301),
+				"permanent": true,
+			},
+			{
+				"level":        "INFO",
+				"msg":          "urlresolver: resolved",
+				"given_url":    srv.URL + "/redirect",
+				"resolved_url": srv.URL + "/target",
+				"outcome":      "ok",
+				"hops":         float64(1),
+			},
+		}, logRecords(t, buf))
+	})
+
+	t.Run("failed resolution", func(t *testing.T) {
+		t.Parallel()
+
+		buf := &bytes.Buffer{}
+		logger := slog.New(slog.NewJSONHandler(buf, nil))
+		resolver := New(newSafeTestTransport(t), 0, WithLogger(logger), WithDomainBlocklist("example.com"))
+
+		_, err := resolver.Resolve(context.Background(), "https://example.com/foo")
+		assert.Error(t, err)
+		records := logRecords(t, buf)
+		assert.Equal(t, 1, len(records))
+		assert.Equal(t, "WARN", records[0]["level"])
+		assert.Equal(t, "blocked", records[0]["outcome"])
+		assert.NotEmpty(t, records[0]["error"])
+	})
+}
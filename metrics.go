@@ -0,0 +1,164 @@
+package urlresolver
+
+import (
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/publicsuffix"
+)
+
+// Metrics reports on the internals of one or more Resolvers, and implements
+// prometheus.Collector so that it may be registered with a Prometheus
+// registry.
+type Metrics struct {
+	inFlight         prometheus.Gauge
+	resolutions      *prometheus.CounterVec
+	duration         *prometheus.HistogramVec
+	requests         *prometheus.CounterVec
+	hops             prometheus.Histogram
+	bodyBytesRead    prometheus.Counter
+	titleExtractions *prometheus.CounterVec
+	interstitials    *prometheus.CounterVec
+}
+
+var _ prometheus.Collector = &Metrics{} // Metrics implements prometheus.Collector
+
+// NewMetrics creates a new Metrics, to be passed to Resolvers via
+// WithMetrics.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "urlresolver_resolutions_in_flight",
+			Help: "Number of resolutions currently in progress, not including coalesced requests.",
+		}),
+		resolutions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "urlresolver_resolutions_total",
+			Help: "Number of resolutions, not including coalesced requests, by outcome.",
+		}, []string{"outcome"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "urlresolver_resolution_duration_seconds",
+			Help:    "Time taken by each resolution, not including coalesced requests, by outcome.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"outcome"}),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "urlresolver_requests_total",
+			Help: "Number of requests to resolve a URL, by whether they were coalesced with an in-progress resolution.",
+		}, []string{"coalesced"}),
+		hops: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "urlresolver_hops",
+			Help:    "Number of redirect and unwrap hops per resolution.",
+			Buckets: prometheus.LinearBuckets(0, 1, 11),
+		}),
+		bodyBytesRead: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "urlresolver_body_bytes_read_total",
+			Help: "Number of response body bytes read while looking for titles.",
+		}),
+		titleExtractions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "urlresolver_title_extractions_total",
+			Help: "Number of attempts to extract a title from a response, by result (found, missing, or error).",
+		}, []string{"result"}),
+		interstitials: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "urlresolver_interstitials_total",
+			Help: "Number of auth or bot detection interstitials detected, by registered domain and matched fingerprint.",
+		}, []string{"domain", "fingerprint"}),
+	}
+}
+
+// WithMetrics makes the resolver report on its internals to the given
+// Metrics, which may be shared between resolvers.
+func WithMetrics(m *Metrics) Option {
+	return func(r *Resolver) {
+		r.metrics = m
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.inFlight.Describe(ch)
+	m.resolutions.Describe(ch)
+	m.duration.Describe(ch)
+	m.requests.Describe(ch)
+	m.hops.Describe(ch)
+	m.bodyBytesRead.Describe(ch)
+	m.titleExtractions.Describe(ch)
+	m.interstitials.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.inFlight.Collect(ch)
+	m.resolutions.Collect(ch)
+	m.duration.Collect(ch)
+	m.requests.Collect(ch)
+	m.hops.Collect(ch)
+	m.bodyBytesRead.Collect(ch)
+	m.titleExtractions.Collect(ch)
+	m.interstitials.Collect(ch)
+}
+
+// The methods below are safe to call on a nil *Metrics, so that resolvers
+// need not check whether metrics are enabled.
+
+func (m *Metrics) resolutionStarted() {
+	if m != nil {
+		m.inFlight.Inc()
+	}
+}
+
+func (m *Metrics) resolutionFinished(result Result, elapsed time.Duration) {
+	if m != nil {
+		m.inFlight.Dec()
+		m.resolutions.WithLabelValues(string(result.Outcome)).Inc()
+		m.duration.WithLabelValues(string(result.Outcome)).Observe(elapsed.Seconds())
+		m.hops.Observe(float64(len(result.Hops)))
+		if result.Interstitial != "" {
+			m.interstitials.WithLabelValues(interstitialDomain(result.ResolvedURL), result.Interstitial).Inc()
+		}
+	}
+}
+
+func (m *Metrics) requestFinished(coalesced bool) {
+	if m != nil {
+		m.requests.WithLabelValues(strconv.FormatBool(coalesced)).Inc()
+	}
+}
+
+func (m *Metrics) bodyRead(n int) {
+	if m != nil {
+		m.bodyBytesRead.Add(float64(n))
+	}
+}
+
+func (m *Metrics) titleExtracted(title string, err error) {
+	if m == nil {
+		return
+	}
+	result := "found"
+	switch {
+	case err != nil:
+		result = "error"
+	case title == "":
+		result = "missing"
+	}
+	m.titleExtractions.WithLabelValues(result).Inc()
+}
+
+// interstitialDomain returns the registered domain (eTLD+1) of the given URL,
+// to keep the cardinality of the interstitials metric bounded.
+func interstitialDomain(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	host := u.Hostname()
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	if domain, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return domain
+	}
+	return host
+}
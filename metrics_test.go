@@ -0,0 +1,61 @@
+package urlresolver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect":
+			http.Redirect(w, r, "/title", http.StatusFound)
+		case "/interstitial":
+			w.Header().Set("Location", "https://www.forbes.com/forbes/welcome/")
+			w.WriteHeader(http.StatusFound)
+		case "/title":
+			w.Write([]byte("<title>title</title>")) //nolint:errcheck
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("no title")) //nolint:errcheck
+		}
+	}))
+	defer srv.Close()
+
+	metrics := NewMetrics()
+	resolver := New(newSafeTestTransport(t), 0, WithMetrics(metrics))
+	for _, path := range []string{"/redirect", "/title", "/untitled", "/interstitial"} {
+		_, err := resolver.Resolve(context.Background(), srv.URL+path)
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.inFlight))
+	assert.Equal(t, 2.0, testutil.ToFloat64(metrics.resolutions.WithLabelValues(string(OutcomeOK))))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.resolutions.WithLabelValues(string(OutcomePartialNoTitle))))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.resolutions.WithLabelValues(string(OutcomeChallengeFallback))))
+	assert.Equal(t, 4.0, testutil.ToFloat64(metrics.requests.WithLabelValues("false")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.requests.WithLabelValues("true")))
+	assert.Equal(t, float64(len("<title>title</title>")*2+len("no title")), testutil.ToFloat64(metrics.bodyBytesRead))
+	assert.Equal(t, 2.0, testutil.ToFloat64(metrics.titleExtractions.WithLabelValues("found")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(metrics.titleExtractions.WithLabelValues("missing")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.titleExtractions.WithLabelValues("error")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.interstitials.WithLabelValues("127.0.0.1", "forbes_welcome")))
+
+	// the collector can be registered and gathered
+	registry := prometheus.NewPedanticRegistry()
+	assert.NoError(t, registry.Register(metrics))
+	count, err := testutil.GatherAndCount(registry, "urlresolver_hops")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	count, err = testutil.GatherAndCount(registry, "urlresolver_resolution_duration_seconds")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count, "one histogram per outcome")
+}
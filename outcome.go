@@ -0,0 +1,98 @@
+package urlresolver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// Outcome summarizes the quality of a Result, so that consumers need not
+// infer it from combinations of empty fields and error values.
+type Outcome string
+
+// Possible outcomes.
+const (
+	// OutcomeOK indicates that the URL was fully resolved and a title was
+	// found.
+	OutcomeOK Outcome = "ok"
+
+	// OutcomeFile indicates that the URL was fully resolved to a non-HTML
+	// resource, which has no title but is described by Result.File.
+	OutcomeFile Outcome = "file"
+
+	// OutcomePartialNoTitle indicates that the URL was fully resolved, but no
+	// title could be found.
+	OutcomePartialNoTitle Outcome = "partial_no_title"
+
+	// OutcomeChallengeFallback indicates that we were redirected to a
+	// well-known auth or bot detection interstitial, so the hop before it was
+	// used as the resolved URL.
+	OutcomeChallengeFallback Outcome = "challenge_fallback"
+
+	// OutcomeTimeoutPartial indicates that resolution timed out, and the
+	// result holds the last URL reached before the timeout.
+	OutcomeTimeoutPartial Outcome = "timeout_partial"
+
+	// OutcomeNotFound indicates that the URL resolved to a 404 Not Found
+	// response.
+	OutcomeNotFound Outcome = "not_found"
+
+	// OutcomeGone indicates that the URL resolved to a 410 Gone response.
+	OutcomeGone Outcome = "gone"
+
+	// OutcomeHTTPError indicates that the URL resolved to any other 4xx or
+	// 5xx response.
+	OutcomeHTTPError Outcome = "http_error"
+
+	// OutcomeBotChallenge indicates that resolution was stopped by a bot
+	// detection challenge (see ErrBotChallenge).
+	OutcomeBotChallenge Outcome = "bot_challenge"
+
+	// OutcomeBlocked indicates that resolution was refused by policy.
+	OutcomeBlocked Outcome = "blocked"
+
+	// OutcomeUnsafeTarget indicates that resolution was stopped because a
+	// redirect led to a private network (see ErrRedirectToPrivateNetwork).
+	OutcomeUnsafeTarget Outcome = "unsafe_target"
+
+	// OutcomeError indicates that resolution failed for any other reason.
+	OutcomeError Outcome = "error"
+)
+
+// classifyOutcome computes the Outcome for a result and the error (if any)
+// returned alongside it.
+func classifyOutcome(result Result, err error) Outcome {
+	if err != nil {
+		var netErr net.Error
+		switch {
+		case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+			return OutcomeTimeoutPartial
+		case errors.Is(err, ErrBotChallenge):
+			return OutcomeBotChallenge
+		case errors.Is(err, ErrRedirectToPrivateNetwork):
+			return OutcomeUnsafeTarget
+		case errors.Is(err, ErrIPLiteralBlocked), errors.Is(err, ErrBlockedDomain):
+			return OutcomeBlocked
+		default:
+			return OutcomeError
+		}
+	}
+	switch {
+	case result.Outcome == OutcomeChallengeFallback:
+		return OutcomeChallengeFallback
+	case result.StatusCode == http.StatusNotFound:
+		return OutcomeNotFound
+	case result.StatusCode == http.StatusGone:
+		return OutcomeGone
+	case result.StatusCode >= 400 && result.StatusCode != http.StatusRequestedRangeNotSatisfiable:
+		// A 416 only means the body was empty (see shouldParseTitle)
+		return OutcomeHTTPError
+	case result.Title == "" && result.File != nil:
+		return OutcomeFile
+	case result.Title == "":
+		return OutcomePartialNoTitle
+	default:
+		return OutcomeOK
+	}
+}
@@ -0,0 +1,101 @@
+package urlresolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestClassifyOutcome(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		result Result
+		err    error
+		want   Outcome
+	}{
+		"title found": {
+			result: Result{Title: "title"},
+			want:   OutcomeOK,
+		},
+		"no title": {
+			result: Result{},
+			want:   OutcomePartialNoTitle,
+		},
+		"file": {
+			result: Result{File: &FileInfo{MIMEType: "application/pdf"}},
+			want:   OutcomeFile,
+		},
+		"challenge fallback": {
+			result: Result{Outcome: OutcomeChallengeFallback},
+			want:   OutcomeChallengeFallback,
+		},
+		"challenge fallback with title": {
+			result: Result{Title: "title", Outcome: OutcomeChallengeFallback},
+			want:   OutcomeChallengeFallback,
+		},
+		"context deadline": {
+			err:  &url.Error{Op: "Get", URL: "/", Err: context.DeadlineExceeded},
+			want: OutcomeTimeoutPartial,
+		},
+		"network timeout": {
+			err:  &url.Error{Op: "Get", URL: "/", Err: timeoutError{}},
+			want: OutcomeTimeoutPartial,
+		},
+		"blocked": {
+			err:  fmt.Errorf("%w: 127.0.0.1", ErrIPLiteralBlocked),
+			want: OutcomeBlocked,
+		},
+		"unsafe target": {
+			err:  fmt.Errorf("%w: http://192.168.0.1/", ErrRedirectToPrivateNetwork),
+			want: OutcomeUnsafeTarget,
+		},
+		"bot challenge": {
+			result: Result{StatusCode: 403},
+			err:    fmt.Errorf("%w: example.com", ErrBotChallenge),
+			want:   OutcomeBotChallenge,
+		},
+		"not found": {
+			result: Result{Title: "title", StatusCode: 404},
+			want:   OutcomeNotFound,
+		},
+		"gone": {
+			result: Result{StatusCode: 410},
+			want:   OutcomeGone,
+		},
+		"server error": {
+			result: Result{Title: "title", StatusCode: 503},
+			want:   OutcomeHTTPError,
+		},
+		"range not satisfiable": {
+			result: Result{StatusCode: 416},
+			want:   OutcomePartialNoTitle,
+		},
+		"challenge fallback after redirect": {
+			result: Result{Outcome: OutcomeChallengeFallback, StatusCode: 302},
+			want:   OutcomeChallengeFallback,
+		},
+		"other error": {
+			result: Result{Title: "title"},
+			err:    errors.New("error"),
+			want:   OutcomeError,
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, classifyOutcome(tc.result, tc.err))
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package urlresolver
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// PageMetadata describes an HTML document, as declared by the <meta> and
+// <link> elements in its <head>.
+type PageMetadata struct {
+	// Description is the document's description, from either its
+	// description or og:description <meta> element.
+	Description string
+
+	// CanonicalURL is the URL given by the document's rel=canonical <link>,
+	// resolved against the document's URL.
+	CanonicalURL string
+
+	// SiteName, ImageURL, and Type are taken from the document's Open Graph
+	// <meta> elements, with ImageURL resolved against the document's URL.
+	SiteName string
+	ImageURL string
+	Type     string
+}
+
+// parsePageMetadata parses the metadata in the <head> of the given HTML
+// document, which was found at the given URL.
+func parsePageMetadata(body []byte, base *url.URL) PageMetadata {
+	var (
+		md            PageMetadata
+		ogDescription string
+	)
+	z := html.NewTokenizer(bytes.NewReader(body))
+tokens:
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			// EOF, since the tokenizer is otherwise lenient
+			break tokens
+		}
+		name, hasAttr := z.TagName()
+		a := atom.Lookup(name)
+		if (tt == html.EndTagToken && a == atom.Head) || (tt == html.StartTagToken && a == atom.Body) {
+			break tokens
+		}
+		if (tt != html.StartTagToken && tt != html.SelfClosingTagToken) || !hasAttr {
+			continue
+		}
+
+		switch a {
+		case atom.Meta:
+			attrs := tagAttrs(z)
+			content := strings.TrimSpace(attrs["content"])
+			switch strings.ToLower(attrs["name"] + attrs["property"]) {
+			case "description":
+				setIfEmpty(&md.Description, content)
+			case "og:description":
+				setIfEmpty(&ogDescription, content)
+			case "og:site_name":
+				setIfEmpty(&md.SiteName, content)
+			case "og:image", "og:image:url":
+				setIfEmpty(&md.ImageURL, resolveReference(base, content))
+			case "og:type":
+				setIfEmpty(&md.Type, content)
+			}
+		case atom.Link:
+			attrs := tagAttrs(z)
+			for _, rel := range strings.Fields(attrs["rel"]) {
+				if strings.EqualFold(rel, "canonical") {
+					setIfEmpty(&md.CanonicalURL, resolveReference(base, strings.TrimSpace(attrs["href"])))
+				}
+			}
+		}
+	}
+
+	setIfEmpty(&md.Description, ogDescription)
+	return md
+}
+
+// tagAttrs returns the attributes of the current tag, with lowercased names.
+func tagAttrs(z *html.Tokenizer) map[string]string {
+	attrs := make(map[string]string)
+	for {
+		key, val, more := z.TagAttr()
+		k := strings.ToLower(string(key))
+		if _, ok := attrs[k]; !ok {
+			attrs[k] = string(val)
+		}
+		if !more {
+			return attrs
+		}
+	}
+}
+
+// setIfEmpty sets the given field to the given value, unless the field
+// already has a value.
+func setIfEmpty(field *string, value string) {
+	if *field == "" {
+		*field = value
+	}
+}
+
+// resolveReference resolves the given reference against the given base URL,
+// returning it unchanged if it can't be parsed.
+func resolveReference(base *url.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	u, err := base.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return u.String()
+}
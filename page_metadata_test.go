@@ -0,0 +1,73 @@
+package urlresolver
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePageMetadata(t *testing.T) {
+	t.Parallel()
+
+	base, _ := url.Parse("https://example.com/articles/1?ref=home")
+
+	testCases := map[string]struct {
+		body string
+		want PageMetadata
+	}{
+		"full metadata": {
+			body: `<html><head>
+				<title>Title</title>
+				<meta name="description" content=" A description. ">
+				<meta property="og:description" content="An OG description.">
+				<meta property="og:site_name" content="Example &amp; Co">
+				<meta property="og:image" content="/images/1.png">
+				<meta property="og:type" content="article">
+				<link rel="canonical" href="https://example.com/articles/1">
+			</head><body></body></html>`,
+			want: PageMetadata{
+				Description:  "A description.",
+				CanonicalURL: "https://example.com/articles/1",
+				SiteName:     "Example & Co",
+				ImageURL:     "https://example.com/images/1.png",
+				Type:         "article",
+			},
+		},
+		"og description as fallback": {
+			body: `<head><meta property="og:description" content="An OG description."></head>`,
+			want: PageMetadata{Description: "An OG description."},
+		},
+		"case insensitive names and self closing tags": {
+			body: `<HEAD><META NAME="Description" CONTENT="Shouty"/><LINK REL="Canonical" HREF="../2"/></HEAD>`,
+			want: PageMetadata{Description: "Shouty", CanonicalURL: "https://example.com/2"},
+		},
+		"first value wins": {
+			body: `<head><meta name="description" content="first"><meta name="description" content="second"></head>`,
+			want: PageMetadata{Description: "first"},
+		},
+		"multiple rel values": {
+			body: `<head><link rel="alternate canonical" href="/canonical"></head>`,
+			want: PageMetadata{CanonicalURL: "https://example.com/canonical"},
+		},
+		"body metadata ignored": {
+			body: `<head><title>Title</title></head><body><meta name="description" content="nope"></body>`,
+			want: PageMetadata{},
+		},
+		"implied head end": {
+			body: `<title>Title</title><body><link rel="canonical" href="/nope">`,
+			want: PageMetadata{},
+		},
+		"truncated": {
+			body: `<head><meta name="description" content="kept"><meta property="og:image" cont`,
+			want: PageMetadata{Description: "kept"},
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, parsePageMetadata([]byte(tc.body), base))
+		})
+	}
+}
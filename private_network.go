@@ -0,0 +1,57 @@
+package urlresolver
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ErrRedirectToPrivateNetwork is returned when a redirect chain leads to a
+// host whose address is in a private, loopback, or otherwise non-public
+// network and the connection to it is blocked by the dialer (e.g. by
+// safedialer.Control, as recommended in the README).
+var ErrRedirectToPrivateNetwork = errors.New("redirect to private network")
+
+// ErrBlockedAddress may be returned (or wrapped) by a dialer's Control
+// function to signal that it refused to connect to an address by policy,
+// so that redirects to private networks are reported as
+// ErrRedirectToPrivateNetwork. Errors from safedialer.Control are
+// recognized without it.
+var ErrBlockedAddress = errors.New("address blocked by dialer")
+
+// wrapPrivateNetworkErr wraps err with ErrRedirectToPrivateNetwork if it is a
+// dial to a non-public address that was blocked by the dialer's policy. Other
+// dial errors, like a refused connection or a timeout, are returned as-is.
+// The offending hop is included in the error message.
+func wrapPrivateNetworkErr(hop string, err error) error {
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) || opErr.Op != "dial" || opErr.Addr == nil || !isBlockedDial(opErr.Err) {
+		return err
+	}
+	host, _, splitErr := net.SplitHostPort(opErr.Addr.String())
+	if splitErr != nil {
+		host = opErr.Addr.String()
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !isNonPublicIP(ip) {
+		return err
+	}
+	return fmt.Errorf("%w: %s (%s): %w", ErrRedirectToPrivateNetwork, hop, ip, err)
+}
+
+// isBlockedDial returns true if the given dial error came from a Control
+// function refusing the connection. safedialer does not export its errors,
+// so they can only be recognized by their message.
+func isBlockedDial(err error) bool {
+	return err != nil && (errors.Is(err, ErrBlockedAddress) || strings.HasSuffix(err.Error(), "is not a public IP address"))
+}
+
+func isNonPublicIP(ip net.IP) bool {
+	return ip.IsPrivate() ||
+		ip.IsLoopback() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsUnspecified()
+}
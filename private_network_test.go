@@ -0,0 +1,113 @@
+package urlresolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrapPrivateNetworkErr(t *testing.T) {
+	t.Parallel()
+
+	dialErr := func(addr net.Addr) error {
+		return &net.OpError{Op: "dial", Net: "tcp", Addr: addr, Err: fmt.Errorf("%w: rejected", ErrBlockedAddress)}
+	}
+	privateAddr := &net.TCPAddr{IP: net.ParseIP("10.1.2.3"), Port: 80}
+
+	testCases := map[string]struct {
+		err  error
+		want bool
+	}{
+		"private ipv4": {
+			err:  dialErr(&net.TCPAddr{IP: net.ParseIP("10.1.2.3"), Port: 80}),
+			want: true,
+		},
+		"loopback": {
+			err:  dialErr(&net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 443}),
+			want: true,
+		},
+		"link local ipv6": {
+			err:  dialErr(&net.TCPAddr{IP: net.ParseIP("fe80::1"), Port: 443}),
+			want: true,
+		},
+		"safedialer error": {
+			err:  &net.OpError{Op: "dial", Net: "tcp4", Addr: privateAddr, Err: errors.New("10.1.2.3 is not a public IP address")},
+			want: true,
+		},
+		"connection refused": {
+			err:  &net.OpError{Op: "dial", Net: "tcp", Addr: privateAddr, Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)},
+			want: false,
+		},
+		"timeout": {
+			err:  &net.OpError{Op: "dial", Net: "tcp", Addr: privateAddr, Err: os.ErrDeadlineExceeded},
+			want: false,
+		},
+		"public address": {
+			err:  dialErr(&net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 80}),
+			want: false,
+		},
+		"non-dial error": {
+			err:  &net.OpError{Op: "read", Net: "tcp", Addr: &net.TCPAddr{IP: net.ParseIP("10.1.2.3")}, Err: errors.New("reset")},
+			want: false,
+		},
+		"other error": {
+			err:  errors.New("other"),
+			want: false,
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			err := wrapPrivateNetworkErr("http://example.com/", tc.err)
+			assert.Equal(t, tc.want, errors.Is(err, ErrRedirectToPrivateNetwork))
+			assert.ErrorIs(t, err, tc.err, "original error should be preserved")
+		})
+	}
+}
+
+func TestRedirectToPrivateNetwork(t *testing.T) {
+	t.Parallel()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://internal.example.com/secret", http.StatusFound)
+	})
+
+	// simulates a transport whose dialer refuses to connect to private
+	// addresses, a la safedialer
+	transport := &testTransport{
+		roundTrip: func(r *http.Request) (*http.Response, error) {
+			if r.URL.Host == "internal.example.com" {
+				return nil, &net.OpError{
+					Op:   "dial",
+					Net:  "tcp4",
+					Addr: &net.TCPAddr{IP: net.ParseIP("192.168.1.1"), Port: 80},
+					Err:  errors.New("bad ip is not a public IP address"),
+				}
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, r)
+			resp := rec.Result()
+			resp.Request = r
+			return resp, nil
+		},
+	}
+
+	resolver := New(transport, 0)
+	result, err := resolver.Resolve(context.Background(), "http://example.com/")
+	assert.True(t, errors.Is(err, ErrRedirectToPrivateNetwork), "expected ErrRedirectToPrivateNetwork, got %v", err)
+	assert.Contains(t, err.Error(), "http://internal.example.com/secret")
+	assert.Equal(t, Result{
+		ResolvedURL:      "http://internal.example.com/secret",
+		IntermediateURLs: []string{"http://example.com/"},
+		Outcome:          OutcomeUnsafeTarget,
+	}, withoutHops(t, result))
+}
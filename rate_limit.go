@@ -0,0 +1,127 @@
+package urlresolver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// ErrRateLimited is returned when an outbound request exceeds the resolver's
+// rate limits, either because WithRateLimitNoWait is used or because waiting
+// for the limit would exceed the request's deadline.
+var ErrRateLimited = errors.New("outbound rate limit exceeded")
+
+// WithRateLimit limits the rate of all outbound requests, including each hop
+// in a redirect chain, to limit requests per second with the given burst.
+func WithRateLimit(limit rate.Limit, burst int) Option {
+	return func(r *Resolver) {
+		r.rateLimiter().global = rate.NewLimiter(limit, burst)
+	}
+}
+
+// WithDomainRateLimit limits the rate of outbound requests to the given
+// domain and any of its subdomains. Requests are subject to both the most
+// specific matching domain limit and the global limit, if any.
+func WithDomainRateLimit(domain string, limit rate.Limit, burst int) Option {
+	return func(r *Resolver) {
+		rl := r.rateLimiter()
+		if rl.domains == nil {
+			rl.domains = make(map[string]*rate.Limiter)
+		}
+		rl.domains[strings.TrimSuffix(strings.ToLower(domain), ".")] = rate.NewLimiter(limit, burst)
+	}
+}
+
+// WithRateLimitNoWait causes outbound requests that exceed a rate limit to
+// fail immediately with ErrRateLimited, instead of waiting their turn.
+func WithRateLimitNoWait() Option {
+	return func(r *Resolver) {
+		r.rateLimiter().noWait = true
+	}
+}
+
+// rateLimiter returns the resolver's rate limiter, creating it if necessary.
+func (r *Resolver) rateLimiter() *rateLimiter {
+	if r.rateLimits == nil {
+		r.rateLimits = &rateLimiter{}
+	}
+	return r.rateLimits
+}
+
+// rateLimiter applies global and per-domain rate limits.
+type rateLimiter struct {
+	global  *rate.Limiter
+	domains map[string]*rate.Limiter
+	noWait  bool
+}
+
+// limitersFor returns the limiters that apply to the given host.
+func (l *rateLimiter) limitersFor(host string) []*rate.Limiter {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+
+	var limiters []*rate.Limiter
+	if l.global != nil {
+		limiters = append(limiters, l.global)
+	}
+
+	var domainLimiter *rate.Limiter
+	matched := ""
+	for domain, limiter := range l.domains {
+		if len(domain) > len(matched) && (host == domain || strings.HasSuffix(host, "."+domain)) {
+			domainLimiter, matched = limiter, domain
+		}
+	}
+	if domainLimiter != nil {
+		limiters = append(limiters, domainLimiter)
+	}
+	return limiters
+}
+
+// rateLimitTransport is an http.RoundTripper that enforces rate limits.
+type rateLimitTransport struct {
+	transport http.RoundTripper
+	limiter   *rateLimiter
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Hostname()
+	limiters := t.limiter.limitersFor(host)
+	if t.limiter.noWait {
+		if !allowAll(limiters) {
+			return nil, fmt.Errorf("%w: %s", ErrRateLimited, host)
+		}
+		return t.transport.RoundTrip(req)
+	}
+	for _, limiter := range limiters {
+		if err := limiter.Wait(req.Context()); err != nil {
+			if ctxErr := req.Context().Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			return nil, fmt.Errorf("%w: %s: %s", ErrRateLimited, host, err)
+		}
+	}
+	return t.transport.RoundTrip(req)
+}
+
+// allowAll spends a token from each of the given limiters if every one of
+// them allows a request right now. Otherwise, no tokens are spent.
+func allowAll(limiters []*rate.Limiter) bool {
+	now := time.Now()
+	reservations := make([]*rate.Reservation, 0, len(limiters))
+	for _, limiter := range limiters {
+		reservation := limiter.ReserveN(now, 1)
+		if !reservation.OK() || reservation.DelayFrom(now) > 0 {
+			reservation.CancelAt(now)
+			for _, prev := range reservations {
+				prev.CancelAt(now)
+			}
+			return false
+		}
+		reservations = append(reservations, reservation)
+	}
+	return true
+}
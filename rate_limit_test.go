@@ -0,0 +1,104 @@
+package urlresolver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestRateLimiterSelection(t *testing.T) {
+	t.Parallel()
+
+	r := New(nil, 0,
+		WithRateLimit(10, 1),
+		WithDomainRateLimit("example.com", 5, 1),
+		WithDomainRateLimit("news.example.com", 1, 1),
+	)
+	l := r.rateLimits
+
+	testCases := map[string][]*rate.Limiter{
+		"other.org":            {l.global},
+		"example.com":          {l.global, l.domains["example.com"]},
+		"WWW.EXAMPLE.COM":      {l.global, l.domains["example.com"]},
+		"news.example.com":     {l.global, l.domains["news.example.com"]},
+		"www.news.example.com": {l.global, l.domains["news.example.com"]},
+		"badexample.com":       {l.global},
+	}
+	for host, want := range testCases {
+		assert.Equal(t, want, l.limitersFor(host), host)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	t.Parallel()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<title>title</title>`)) //nolint:errcheck
+	})
+
+	t.Run("requests wait for their turn", func(t *testing.T) {
+		t.Parallel()
+
+		resolver := New(newHandlerTestTransport(t, "example.com", handler), 0,
+			WithRateLimit(rate.Every(50*time.Millisecond), 1))
+
+		start := time.Now()
+		for _, path := range []string{"/a", "/b", "/c"} {
+			result, err := resolver.Resolve(context.Background(), "http://example.com"+path)
+			assert.NoError(t, err)
+			assert.Equal(t, "title", result.Title)
+		}
+		assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	})
+
+	t.Run("no wait rejects excess requests", func(t *testing.T) {
+		t.Parallel()
+
+		resolver := New(newHandlerTestTransport(t, "example.com", handler), 0,
+			WithDomainRateLimit("example.com", rate.Every(time.Hour), 1),
+			WithRateLimitNoWait())
+
+		_, err := resolver.Resolve(context.Background(), "http://example.com/a")
+		assert.NoError(t, err)
+
+		result, err := resolver.Resolve(context.Background(), "http://example.com/b")
+		assert.True(t, errors.Is(err, ErrRateLimited), "expected ErrRateLimited, got %v", err)
+		assert.Equal(t, "http://example.com/b", result.ResolvedURL)
+	})
+
+	t.Run("no wait rejection by domain limit does not spend global token", func(t *testing.T) {
+		t.Parallel()
+
+		resolver := New(newHandlerTestTransport(t, "example.com", handler), 0,
+			WithRateLimit(rate.Every(time.Hour), 2),
+			WithDomainRateLimit("example.com", rate.Every(time.Hour), 1),
+			WithRateLimitNoWait())
+
+		_, err := resolver.Resolve(context.Background(), "http://example.com/a")
+		assert.NoError(t, err)
+
+		_, err = resolver.Resolve(context.Background(), "http://example.com/b")
+		assert.True(t, errors.Is(err, ErrRateLimited), "expected ErrRateLimited, got %v", err)
+		assert.InDelta(t, 1, resolver.rateLimits.global.Tokens(), 0.01)
+	})
+
+	t.Run("wait exceeding deadline is rejected", func(t *testing.T) {
+		t.Parallel()
+
+		resolver := New(newHandlerTestTransport(t, "example.com", handler), 0,
+			WithRateLimit(rate.Every(time.Hour), 1))
+
+		_, err := resolver.Resolve(context.Background(), "http://example.com/a")
+		assert.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_, err = resolver.Resolve(ctx, "http://example.com/b")
+		assert.True(t, errors.Is(err, ErrRateLimited), "expected ErrRateLimited, got %v", err)
+	})
+}
@@ -0,0 +1,63 @@
+package urlresolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrTooManyRedirects is returned (wrapped in a *TooManyRedirectsError) when
+// a redirect chain is longer than a maximum set with WithMaxRedirects or
+// ContextWithMaxRedirects.
+var ErrTooManyRedirects = errors.New("too many redirects")
+
+// TooManyRedirectsError describes a redirect chain that was abandoned for
+// being too long.
+type TooManyRedirectsError struct {
+	// Max is the maximum number of redirects that was exceeded.
+	Max int
+	// URLs are the URLs visited before giving up, followed by the redirect
+	// target that was not followed.
+	URLs []string
+}
+
+func (e *TooManyRedirectsError) Error() string {
+	return fmt.Sprintf("%s: exceeded maximum of %d", ErrTooManyRedirects, e.Max)
+}
+
+// Unwrap allows errors.Is(err, ErrTooManyRedirects) to match.
+func (e *TooManyRedirectsError) Unwrap() error {
+	return ErrTooManyRedirects
+}
+
+// WithMaxRedirects sets the maximum number of redirects that will be followed
+// when resolving a URL. Longer redirect chains fail with a
+// *TooManyRedirectsError; if n is 0, any redirect fails. It may be
+// overridden for individual requests with ContextWithMaxRedirects.
+//
+// Without this option, the resolver stops after a few redirects and reports
+// the last redirect response as its result, without an error.
+func WithMaxRedirects(n int) Option {
+	return func(r *Resolver) {
+		r.maxRedirects = n
+		r.maxRedirectsSet = true
+	}
+}
+
+type maxRedirectsKey struct{}
+
+// ContextWithMaxRedirects returns a copy of ctx that overrides the resolver's
+// maximum number of redirects for any URL resolved with it, as if it had been
+// set with WithMaxRedirects.
+func ContextWithMaxRedirects(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, maxRedirectsKey{}, n)
+}
+
+// maxRedirectsFor returns the maximum number of redirects to follow for the
+// given request context, and whether that maximum was set explicitly.
+func (r *Resolver) maxRedirectsFor(ctx context.Context) (int, bool) {
+	if n, ok := ctx.Value(maxRedirectsKey{}).(int); ok {
+		return n, true
+	}
+	return r.maxRedirects, r.maxRedirectsSet
+}
@@ -0,0 +1,112 @@
+package urlresolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxRedirects(t *testing.T) {
+	t.Parallel()
+
+	// redirects /0 -> /1 -> /2 ... until /n, which returns a title
+	newServer := func(t *testing.T, n int) *httptest.Server {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			i, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+			if i >= n {
+				w.Write([]byte(`<title>done</title>`)) //nolint:errcheck
+				return
+			}
+			http.Redirect(w, r, fmt.Sprintf("/%d", i+1), http.StatusFound)
+		}))
+		t.Cleanup(srv.Close)
+		return srv
+	}
+
+	testCases := map[string]struct {
+		opts         []Option
+		ctxMax       int // if > 0, overrides max redirects via context
+		redirects    int
+		wantErrURLs  []string
+		wantStopped  bool // if true, the chain is silently cut short
+		wantResolved string
+	}{
+		"default limit allows a short chain": {
+			redirects:    defaultMaxRedirects - 1,
+			wantResolved: "/4",
+		},
+		"default limit stops a long chain without an error": {
+			redirects:    defaultMaxRedirects,
+			wantStopped:  true,
+			wantResolved: "/4",
+		},
+		"resolver option raises limit": {
+			opts:         []Option{WithMaxRedirects(10)},
+			redirects:    10,
+			wantResolved: "/10",
+		},
+		"resolver option lowers limit": {
+			opts:         []Option{WithMaxRedirects(2)},
+			redirects:    3,
+			wantErrURLs:  []string{"/0", "/1", "/2", "/3"},
+			wantResolved: "/3",
+		},
+		"no redirects allowed": {
+			opts:         []Option{WithMaxRedirects(0)},
+			redirects:    1,
+			wantErrURLs:  []string{"/0", "/1"},
+			wantResolved: "/1",
+		},
+		"context overrides resolver option": {
+			opts:         []Option{WithMaxRedirects(2)},
+			ctxMax:       10,
+			redirects:    10,
+			wantResolved: "/10",
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := newServer(t, tc.redirects)
+			resolver := New(newSafeTestTransport(t), 0, tc.opts...)
+
+			ctx := context.Background()
+			if tc.ctxMax > 0 {
+				ctx = ContextWithMaxRedirects(ctx, tc.ctxMax)
+			}
+			result, err := resolver.Resolve(ctx, srv.URL+"/0")
+			assert.Equal(t, srv.URL+tc.wantResolved, result.ResolvedURL)
+
+			if tc.wantStopped {
+				assert.NoError(t, err)
+				assert.Equal(t, "", result.Title)
+				assert.Equal(t, OutcomePartialNoTitle, result.Outcome)
+				return
+			}
+			if tc.wantErrURLs == nil {
+				assert.NoError(t, err)
+				assert.Equal(t, "done", result.Title)
+				return
+			}
+
+			assert.ErrorIs(t, err, ErrTooManyRedirects)
+			var redirectsErr *TooManyRedirectsError
+			if assert.True(t, errors.As(err, &redirectsErr)) {
+				for i, u := range tc.wantErrURLs {
+					tc.wantErrURLs[i] = srv.URL + u
+				}
+				assert.Equal(t, tc.wantErrURLs, redirectsErr.URLs)
+			}
+			assert.Equal(t, OutcomeError, result.Outcome)
+		})
+	}
+}
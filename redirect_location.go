@@ -0,0 +1,131 @@
+package urlresolver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mccutchen/urlresolver/internal/urlutil"
+)
+
+// maxLocationLength is the longest Location header we are willing to follow.
+// Legitimate redirects, even those stuffed with tracking params, are far
+// shorter than this.
+const maxLocationLength = 16 * 1024
+
+// ErrInvalidLocation is returned when a redirect response has a Location
+// header that is too long or too malformed to follow.
+var ErrInvalidLocation = errors.New("invalid redirect location")
+
+// locationCheckingTransport is an http.RoundTripper that normalizes and
+// validates the Location header of redirect responses before the http.Client
+// gets a chance to parse and follow them.
+type locationCheckingTransport struct {
+	transport http.RoundTripper
+}
+
+func (t *locationCheckingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil || !isRedirect(resp.StatusCode) {
+		return resp, err
+	}
+
+	loc := resp.Header.Get("Location")
+	if len(loc) > maxLocationLength {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: length %d exceeds maximum of %d", ErrInvalidLocation, len(loc), maxLocationLength)
+	}
+	if normalized := normalizeLocation(loc); normalized != loc {
+		resp.Header.Set("Location", normalized)
+	}
+	if err := checkLocation(resp.Header.Get("Location")); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
+
+// isRedirect returns true if the given status code is one that the
+// http.Client will follow.
+func isRedirect(statusCode int) bool {
+	switch statusCode {
+	case http.StatusMovedPermanently,
+		http.StatusFound,
+		http.StatusSeeOther,
+		http.StatusTemporaryRedirect,
+		http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// checkLocation ensures that a Location header value is of a reasonable
+// length and can be parsed as a URL.
+func checkLocation(loc string) error {
+	if len(loc) > maxLocationLength {
+		return fmt.Errorf("%w: length %d exceeds maximum of %d", ErrInvalidLocation, len(loc), maxLocationLength)
+	}
+	if _, err := url.Parse(loc); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidLocation, err)
+	}
+	return nil
+}
+
+// normalizeLocation fixes up common mistakes in Location headers that
+// browsers tolerate but that net/url rejects or that would produce an
+// invalid request:
+//
+//   - unescaped spaces, control characters, and non-ASCII bytes (e.g. raw
+//     UTF-8) are percent-encoded
+//   - stray % signs that do not start a valid escape sequence are encoded
+//   - internationalized hostnames are converted to punycode
+//
+// Dot segments in the path need no special handling here, because they are
+// resolved when the http.Client resolves the Location against the request
+// URL.
+func normalizeLocation(loc string) string {
+	loc = strings.TrimSpace(loc)
+
+	// only the part after the scheme and host (if any) is escaped, so that
+	// the delimiters of the authority are untouched
+	prefix, rest := "", loc
+	if idx := strings.Index(loc, "//"); idx == 0 || (idx > 0 && loc[idx-1] == ':' && !strings.ContainsAny(loc[:idx], "/?#")) {
+		authorityStart := idx + 2
+		authorityEnd := len(loc)
+		if end := strings.IndexAny(loc[authorityStart:], "/?#"); end >= 0 {
+			authorityEnd = authorityStart + end
+		}
+		prefix = loc[:authorityStart] + urlutil.ASCIIAuthority(loc[authorityStart:authorityEnd])
+		rest = loc[authorityEnd:]
+	}
+
+	var b strings.Builder
+	b.Grow(len(loc))
+	b.WriteString(prefix)
+	for i := 0; i < len(rest); i++ {
+		c := rest[i]
+		switch {
+		case c == '%' && (i+2 >= len(rest) || !isHex(rest[i+1]) || !isHex(rest[i+2])):
+			b.WriteString("%25")
+		case c <= ' ' || c >= 0x7f:
+			fmt.Fprintf(&b, "%%%02X", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func isHex(c byte) bool {
+	switch {
+	case '0' <= c && c <= '9':
+		return true
+	case 'a' <= c && c <= 'f':
+		return true
+	case 'A' <= c && c <= 'F':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+package urlresolver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckLocation(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		given   string
+		wantErr bool
+	}{
+		"empty":                   {given: ""},
+		"absolute":                {given: "https://example.com/foo?bar=baz"},
+		"relative":                {given: "/foo/bar"},
+		"valid escapes":           {given: "/foo%20bar?q=%E2%9C%93"},
+		"max length":              {given: "/" + strings.Repeat("a", maxLocationLength-1)},
+		"too long":                {given: "/" + strings.Repeat("a", maxLocationLength), wantErr: true},
+		"invalid escape":          {given: "/foo%zzbar", wantErr: true},
+		"truncated escape":        {given: "/foo%2", wantErr: true},
+		"trailing percent":        {given: "/foo%", wantErr: true},
+		"escaped percent is fine": {given: "/100%25"},
+		"invalid host":            {given: "http://bad host/", wantErr: true},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			err := checkLocation(tc.given)
+			if tc.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidLocation)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestInvalidLocationRedirects(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]string{
+		"too long":     "/" + strings.Repeat("a", maxLocationLength*4),
+		"invalid host": "http://bad host/",
+	}
+	for name, location := range testCases {
+		location := location
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/a":
+					http.Redirect(w, r, "/b", http.StatusFound)
+				case "/b":
+					w.Header().Set("Location", location)
+					w.WriteHeader(http.StatusFound)
+				}
+			}))
+			defer srv.Close()
+
+			resolver := New(newSafeTestTransport(t), 0)
+			result, err := resolver.Resolve(context.Background(), srv.URL+"/a")
+			assert.True(t, errors.Is(err, ErrInvalidLocation), "expected ErrInvalidLocation, got %v", err)
+
+			// we still get a partial result pointing at the last good hop
+			assert.Equal(t, Result{
+				ResolvedURL:      srv.URL + "/b",
+				IntermediateURLs: []string{srv.URL + "/a"},
+				Outcome:          OutcomeError,
+			}, withoutHops(t, result))
+		})
+	}
+}
+
+func TestNormalizeLocation(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		given string
+		want  string
+	}{
+		"unchanged":              {given: "https://example.com/foo?bar=baz#frag", want: "https://example.com/foo?bar=baz#frag"},
+		"unchanged relative":     {given: "../foo/./bar", want: "../foo/./bar"},
+		"surrounding whitespace": {given: " /foo\r\n", want: "/foo"},
+		"spaces":                 {given: "/foo bar?q=a b", want: "/foo%20bar?q=a%20b"},
+		"raw utf-8":              {given: "/café?q=ü", want: "/caf%C3%A9?q=%C3%BC"},
+		"control characters":     {given: "/a\tb", want: "/a%09b"},
+		"invalid escape":         {given: "/100%zz", want: "/100%25zz"},
+		"truncated escape":       {given: "/foo%2", want: "/foo%252"},
+		"valid escapes kept":     {given: "/foo%20bar%E2%9C%93", want: "/foo%20bar%E2%9C%93"},
+		"idn host":               {given: "https://münchen.de/straße", want: "https://xn--mnchen-3ya.de/stra%C3%9Fe"},
+		"idn host with port":     {given: "https://user@münchen.de:8080/", want: "https://user@xn--mnchen-3ya.de:8080/"},
+		"scheme-relative":        {given: "//münchen.de/ä", want: "//xn--mnchen-3ya.de/%C3%A4"},
+		"url in query":           {given: "/go?to=http://münchen.de/", want: "/go?to=http://m%C3%BCnchen.de/"},
+		"double slash in path":   {given: "/a//b c", want: "/a//b%20c"},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got := normalizeLocation(tc.given)
+			assert.Equal(t, tc.want, got)
+			assert.NoError(t, checkLocation(got))
+		})
+	}
+}
+
+func TestMalformedLocationRedirects(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		location string
+		wantPath string
+	}{
+		"raw utf-8 and spaces": {
+			location: "/café menu?q=a b",
+			wantPath: "/caf%C3%A9%20menu?q=a+b",
+		},
+		"invalid escape": {
+			location: "/100%zz",
+			wantPath: "/100%25zz",
+		},
+		"odd dot segments": {
+			location: "../../../c/./d/../e",
+			wantPath: "/c/e",
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/a/b" {
+					w.Header().Set("Location", tc.location)
+					w.WriteHeader(http.StatusFound)
+					return
+				}
+				w.Write([]byte("<title>ok</title>")) //nolint:errcheck
+			}))
+			defer srv.Close()
+
+			resolver := New(newSafeTestTransport(t), 0)
+			result, err := resolver.Resolve(context.Background(), srv.URL+"/a/b")
+			assert.NoError(t, err)
+			assert.Equal(t, Result{
+				ResolvedURL:      srv.URL + tc.wantPath,
+				Title:            "ok",
+				IntermediateURLs: []string{srv.URL + "/a/b"},
+				Outcome:          OutcomeOK,
+				StatusCode:       200,
+			}, withoutHops(t, result))
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package urlresolver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrRedirectLoop is returned (wrapped in a *RedirectLoopError) when a
+// redirect chain is found to loop.
+var ErrRedirectLoop = errors.New("redirect loop")
+
+// RedirectLoopError describes a redirect loop.
+type RedirectLoopError struct {
+	// URLs are the canonicalized members of the loop, in the order they were
+	// visited, starting and ending with the same URL.
+	URLs []string
+}
+
+func (e *RedirectLoopError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrRedirectLoop, strings.Join(e.URLs, " -> "))
+}
+
+// Unwrap allows errors.Is(err, ErrRedirectLoop) to match.
+func (e *RedirectLoopError) Unwrap() error {
+	return ErrRedirectLoop
+}
+
+// checkRedirectLoop returns a *RedirectLoopError if the upcoming request
+// would revisit any URL already in the redirect chain (e.g. A -> B -> A),
+// comparing URLs after canonicalizing them with the given function.
+func checkRedirectLoop(req *http.Request, via []*http.Request, canonicalize func(*url.URL) string) error {
+	chain := make([]string, 0, len(via)+1)
+	for _, r := range via {
+		chain = append(chain, canonicalizeCopy(canonicalize, r.URL))
+	}
+	target := canonicalizeCopy(canonicalize, req.URL)
+	chain = append(chain, target)
+
+	for i, u := range chain[:len(chain)-1] {
+		if u == target {
+			return &RedirectLoopError{URLs: chain[i:]}
+		}
+	}
+	return nil
+}
+
+// canonicalizeCopy canonicalizes a copy of the given URL, leaving the
+// original untouched.
+func canonicalizeCopy(canonicalize func(*url.URL) string, u *url.URL) string {
+	c := *u
+	return canonicalize(&c)
+}
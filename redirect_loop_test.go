@@ -0,0 +1,110 @@
+package urlresolver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mccutchen/urlresolver/canonical"
+)
+
+func TestRedirectLoops(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		handler      http.HandlerFunc
+		wantLoopURLs []string
+		wantResult   Result
+	}{
+		"A -> B -> A": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/a":
+					http.Redirect(w, r, "/b?utm_source=loop", http.StatusFound)
+				case "/b":
+					http.Redirect(w, r, "/a", http.StatusFound)
+				}
+			},
+			wantLoopURLs: []string{"/a", "/b", "/a"},
+			wantResult: Result{
+				ResolvedURL:      "/a",
+				IntermediateURLs: []string{"/a", "/b?utm_source=loop"},
+				Outcome:          OutcomeError,
+			},
+		},
+		"self loop": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, "/a", http.StatusFound)
+			},
+			wantLoopURLs: []string{"/a", "/a"},
+			wantResult: Result{
+				ResolvedURL:      "/a",
+				IntermediateURLs: []string{"/a"},
+				Outcome:          OutcomeError,
+			},
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewServer(tc.handler)
+			defer srv.Close()
+
+			resolver := New(newSafeTestTransport(t), 0)
+			result, err := resolver.Resolve(context.Background(), srv.URL+"/a")
+
+			if tc.wantLoopURLs != nil {
+				var loopErr *RedirectLoopError
+				if assert.True(t, errors.As(err, &loopErr), "expected *RedirectLoopError, got %v", err) {
+					for idx, u := range tc.wantLoopURLs {
+						tc.wantLoopURLs[idx] = renderURL(srv.URL, u)
+					}
+					assert.Equal(t, tc.wantLoopURLs, loopErr.URLs)
+					assert.ErrorIs(t, err, ErrRedirectLoop)
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+
+			tc.wantResult.ResolvedURL = renderURL(srv.URL, tc.wantResult.ResolvedURL)
+			for idx, hop := range tc.wantResult.IntermediateURLs {
+				tc.wantResult.IntermediateURLs[idx] = renderURL(srv.URL, hop)
+			}
+			assert.Equal(t, tc.wantResult, withoutHops(t, result))
+		})
+	}
+}
+
+func TestRedirectLoopsWithCanonicalizer(t *testing.T) {
+	t.Parallel()
+
+	// A loop that only repeats once the nonce param is ignored
+	var nonce atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b?nonce="+strconv.FormatInt(nonce.Add(1), 10), http.StatusFound)
+		case "/b":
+			http.Redirect(w, r, "/a?nonce="+strconv.FormatInt(nonce.Add(1), 10), http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+
+	canonicalizer, err := canonical.New(canonical.WithParamRules(canonical.ParamRules{{ExcludeParams: []string{"nonce"}}}))
+	assert.NoError(t, err)
+
+	resolver := New(newSafeTestTransport(t), 0, WithCanonicalizer(canonicalizer))
+	_, err = resolver.Resolve(context.Background(), srv.URL+"/a")
+	var loopErr *RedirectLoopError
+	if assert.True(t, errors.As(err, &loopErr), "expected *RedirectLoopError, got %v", err) {
+		assert.Equal(t, []string{srv.URL + "/a", srv.URL + "/b", srv.URL + "/a"}, loopErr.URLs)
+	}
+}
@@ -0,0 +1,77 @@
+package urlresolver
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrResponseTooLarge is returned when a response body exceeds the maximum
+// size configured via WithMaxResponseSize.
+var ErrResponseTooLarge = errors.New("response too large")
+
+// WithMaxResponseSize caps the number of bytes that will be read from any
+// single response. A response is aborted as soon as more than n bytes of its
+// body are read, which protects against huge or endless streaming responses.
+// The cap applies to bytes actually read rather than to the declared
+// Content-Length, so metadata is still reported for large files whose bodies
+// are never read in full.
+//
+// By default, no cap is applied beyond the limited amount of the body that is
+// read to find a title.
+func WithMaxResponseSize(n int64) Option {
+	return func(r *Resolver) {
+		r.maxResponseSize = n
+	}
+}
+
+// maxSizeTransport is an http.RoundTripper that enforces a maximum response
+// size.
+type maxSizeTransport struct {
+	transport http.RoundTripper
+	maxSize   int64
+}
+
+func (t *maxSizeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = &maxSizeReader{
+		rc:        resp.Body,
+		remaining: t.maxSize,
+		maxSize:   t.maxSize,
+	}
+	return resp, nil
+}
+
+// maxSizeReader wraps a response body, returning ErrResponseTooLarge and
+// closing the underlying body once more than maxSize bytes are available.
+type maxSizeReader struct {
+	rc        io.ReadCloser
+	remaining int64
+	maxSize   int64
+}
+
+func (r *maxSizeReader) Read(p []byte) (int, error) {
+	if r.remaining < 0 {
+		return 0, fmt.Errorf("%w: exceeded maximum of %d bytes", ErrResponseTooLarge, r.maxSize)
+	}
+	// Read up to one byte past the limit, so that we can distinguish a body
+	// of exactly maxSize bytes from one that exceeds it.
+	if int64(len(p)) > r.remaining+1 {
+		p = p[:r.remaining+1]
+	}
+	n, err := r.rc.Read(p)
+	r.remaining -= int64(n)
+	if r.remaining < 0 {
+		r.rc.Close()
+		return n + int(r.remaining), fmt.Errorf("%w: exceeded maximum of %d bytes", ErrResponseTooLarge, r.maxSize)
+	}
+	return n, err
+}
+
+func (r *maxSizeReader) Close() error {
+	return r.rc.Close()
+}
@@ -0,0 +1,133 @@
+package urlresolver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxSizeReader(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		body    string
+		maxSize int64
+		want    string
+		wantErr bool
+	}{
+		"under limit":    {body: "abc", maxSize: 10, want: "abc"},
+		"exactly limit":  {body: "abcdefghij", maxSize: 10, want: "abcdefghij"},
+		"over limit":     {body: "abcdefghijk", maxSize: 10, want: "abcdefghij", wantErr: true},
+		"way over limit": {body: strings.Repeat("a", 1000), maxSize: 10, want: "aaaaaaaaaa", wantErr: true},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			r := &maxSizeReader{
+				rc:        io.NopCloser(strings.NewReader(tc.body)),
+				remaining: tc.maxSize,
+				maxSize:   tc.maxSize,
+			}
+			got, err := io.ReadAll(r)
+			assert.Equal(t, tc.want, string(got))
+			if tc.wantErr {
+				assert.ErrorIs(t, err, ErrResponseTooLarge)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestMaxResponseSize(t *testing.T) {
+	t.Parallel()
+
+	body := "<title>title</title>" + strings.Repeat("*", 1024)
+
+	testCases := map[string]struct {
+		handler http.HandlerFunc
+		maxSize int64
+		wantErr error
+		want    Result
+	}{
+		"response within limit": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(body)) //nolint:errcheck
+			},
+			maxSize: 2048,
+			want: Result{
+				Title:      "title",
+				Outcome:    OutcomeOK,
+				StatusCode: 200,
+			},
+		},
+		"content length exceeds limit": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+				w.Write([]byte(body)) //nolint:errcheck
+			},
+			maxSize: 512,
+			wantErr: ErrResponseTooLarge,
+			want: Result{
+				Outcome:    OutcomeError,
+				StatusCode: 200,
+			},
+		},
+		"large file is not rejected": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				// ignores our Range request
+				w.Header().Set("Content-Type", "application/pdf")
+				w.Header().Set("Content-Length", strconv.Itoa(1<<20))
+				w.Write(make([]byte, 1<<20)) //nolint:errcheck
+			},
+			maxSize: 512,
+			want: Result{
+				Outcome:    OutcomeFile,
+				StatusCode: 200,
+				File: &FileInfo{
+					MIMEType: "application/pdf",
+					Size:     1 << 20,
+				},
+			},
+		},
+		"streamed body exceeds limit": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.(http.Flusher).Flush()
+				w.Write([]byte(body)) //nolint:errcheck
+			},
+			maxSize: 512,
+			wantErr: ErrResponseTooLarge,
+			want: Result{
+				Outcome:    OutcomeError,
+				StatusCode: 200,
+			},
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewServer(tc.handler)
+			defer srv.Close()
+
+			resolver := New(newSafeTestTransport(t), 0, WithMaxResponseSize(tc.maxSize))
+			result, err := resolver.Resolve(context.Background(), srv.URL)
+			if tc.wantErr != nil {
+				assert.True(t, errors.Is(err, tc.wantErr), "expected %v, got %v", tc.wantErr, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			tc.want.ResolvedURL = srv.URL
+			assert.Equal(t, tc.want, withoutHops(t, result))
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package urlresolver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	robotsMaxRedirects = 5 // per RFC 9309
+	robotsTTL          = 1 * time.Hour
+	robotsErrorTTL     = 1 * time.Minute
+	robotsMaxEntries   = 10000
+	robotsMaxBodySize  = 500 * 1024 // same limit google applies to robots.txt
+)
+
+// WithRobotsTxt enables robots.txt compliance. Before extracting a title from
+// the final response, the resolver consults (and caches) the target host's
+// robots.txt, and skips title extraction if the path is disallowed for the
+// given user agent. The resolved URL is still reported, and
+// Result.RobotsDisallowed is set.
+func WithRobotsTxt(userAgent string) Option {
+	return func(r *Resolver) {
+		r.robots = &robotsChecker{
+			userAgent: userAgent,
+			entries:   make(map[string]robotsEntry),
+		}
+	}
+}
+
+// robotsClient returns a client for fetching robots.txt files, which follows
+// a limited number of redirects and checks each one against the resolver's
+// policies.
+//
+// robots.txt is checked while the final response's body is still open, and
+// so still holding that host's concurrency slot, so the client does not wait
+// on the per-host concurrency limit.
+func (r *Resolver) robotsClient() *http.Client {
+	client := *r.client
+	if r.hostLimiter != nil {
+		client.Transport = r.roundTripper(nil)
+	}
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) > robotsMaxRedirects {
+			return fmt.Errorf("stopped after %d redirects", robotsMaxRedirects)
+		}
+		return r.checkURL(req.URL)
+	}
+	return &client
+}
+
+// robotsChecker fetches, caches, and evaluates robots.txt rules.
+type robotsChecker struct {
+	userAgent string
+
+	mu      sync.Mutex
+	entries map[string]robotsEntry
+}
+
+type robotsEntry struct {
+	rules   robotsRules
+	expires time.Time
+}
+
+// allowed returns true if the given URL may be fetched according to its
+// host's robots.txt.
+func (c *robotsChecker) allowed(ctx context.Context, client *http.Client, u *url.URL) bool {
+	key := u.Scheme + "://" + u.Host
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+
+	if !ok || time.Now().After(entry.expires) {
+		rules, ttl := c.fetch(ctx, client, key)
+		entry = robotsEntry{
+			rules:   rules,
+			expires: time.Now().Add(ttl),
+		}
+		if ttl > 0 {
+			c.mu.Lock()
+			if len(c.entries) >= robotsMaxEntries {
+				for k := range c.entries {
+					delete(c.entries, k)
+					break
+				}
+			}
+			c.entries[key] = entry
+			c.mu.Unlock()
+		}
+	}
+
+	path := u.EscapedPath()
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return entry.rules.allowed(path)
+}
+
+// fetch fetches and parses the robots.txt file for the given origin, and
+// returns how long its rules should be cached. Per RFC 9309, an unavailable
+// robots.txt (4xx) allows everything, while a server error (5xx) disallows
+// everything, and is only cached briefly.
+//
+// A failed request tells us nothing about the site's wishes, and may be down
+// to this resolution's own context, so it allows everything and isn't cached.
+func (c *robotsChecker) fetch(ctx context.Context, client *http.Client, origin string) (robotsRules, time.Duration) {
+	req, err := http.NewRequestWithContext(ctx, "GET", origin+"/robots.txt", nil)
+	if err != nil {
+		return nil, 0
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 500:
+		return disallowAllRules, robotsErrorTTL
+	case resp.StatusCode >= 400:
+		return nil, robotsTTL
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, robotsMaxBodySize))
+	if err != nil {
+		return nil, 0
+	}
+	return parseRobots(body, c.userAgent), robotsTTL
+}
+
+// robotsRule is a single Allow or Disallow line.
+type robotsRule struct {
+	allow   bool
+	pattern string
+	re      *regexp.Regexp
+}
+
+// robotsRules are the rules that apply to a single user agent.
+type robotsRules []robotsRule
+
+var disallowAllRules = robotsRules{{allow: false, pattern: "/", re: robotsPatternRegexp("/")}}
+
+// allowed returns true if the given path (including any query string) is
+// allowed. The most specific (i.e. longest) matching rule wins, and Allow
+// wins ties.
+func (rules robotsRules) allowed(path string) bool {
+	allowed, matchLen := true, -1
+	for _, rule := range rules {
+		if !rule.re.MatchString(path) {
+			continue
+		}
+		if len(rule.pattern) > matchLen || (len(rule.pattern) == matchLen && rule.allow) {
+			allowed, matchLen = rule.allow, len(rule.pattern)
+		}
+	}
+	return allowed
+}
+
+// parseRobots parses a robots.txt file, returning the rules from the group
+// that most specifically matches the given user agent, falling back to the
+// rules for "*".
+func parseRobots(body []byte, userAgent string) robotsRules {
+	var (
+		groups       = make(map[string]robotsRules)
+		currentUAs   []string
+		inUserAgents bool
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx := strings.IndexByte(line, '#'); idx >= 0 {
+			line = line[:idx]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// consecutive user-agent lines share a group
+			if !inUserAgents {
+				currentUAs = nil
+			}
+			ua := strings.ToLower(value)
+			currentUAs = append(currentUAs, ua)
+			inUserAgents = true
+			// a group with no rules still exists, and allows everything
+			if _, ok := groups[ua]; !ok {
+				groups[ua] = robotsRules{}
+			}
+		case "allow", "disallow":
+			inUserAgents = false
+			// an empty Disallow means allow everything, which is the default
+			if value == "" {
+				continue
+			}
+			rule := robotsRule{
+				allow:   key == "allow",
+				pattern: value,
+				re:      robotsPatternRegexp(value),
+			}
+			for _, ua := range currentUAs {
+				groups[ua] = append(groups[ua], rule)
+			}
+		default:
+			inUserAgents = false
+		}
+	}
+
+	userAgent = strings.ToLower(userAgent)
+	matched := ""
+	for ua := range groups {
+		if ua != "*" && strings.Contains(userAgent, ua) && len(ua) > len(matched) {
+			matched = ua
+		}
+	}
+	if matched != "" {
+		return groups[matched]
+	}
+	return groups["*"]
+}
+
+// robotsPatternRegexp converts a robots.txt path pattern, which may contain
+// * wildcards and a $ end anchor, into a regular expression.
+func robotsPatternRegexp(pattern string) *regexp.Regexp {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	expr := "^" + strings.Join(parts, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return regexp.MustCompile(expr)
+}
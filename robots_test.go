@@ -0,0 +1,247 @@
+package urlresolver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testRobotsTxt = `
+# comments are ignored
+User-agent: *
+Disallow: /private/
+Allow: /private/public-page
+Disallow: /*.pdf$
+
+User-agent: otherbot
+User-agent: urlresolver
+Disallow: /no-resolvers
+Disallow:
+
+User-agent: urlresolver-extra
+Disallow: /
+
+User-agent: permissivebot
+Disallow:
+`
+
+func TestParseRobots(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		userAgent string
+		path      string
+		want      bool
+	}{
+		"default allowed":                {"somebot", "/foo", true},
+		"default disallowed":             {"somebot", "/private/foo", false},
+		"longer allow wins":              {"somebot", "/private/public-page", true},
+		"wildcard with anchor":           {"somebot", "/docs/report.pdf", false},
+		"wildcard with anchor no match":  {"somebot", "/docs/report.pdf?download=1", true},
+		"specific group":                 {"urlresolver", "/no-resolvers", false},
+		"specific group ignores default": {"urlresolver", "/private/foo", true},
+		"shared group":                   {"otherbot", "/no-resolvers", false},
+		"most specific group":            {"urlresolver-extra/1.0", "/foo", false},
+		"user agent is case insensitive": {"URLResolver", "/no-resolvers", false},
+		"group without rules":            {"permissivebot", "/private/foo", true},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			rules := parseRobots([]byte(testRobotsTxt), tc.userAgent)
+			assert.Equal(t, tc.want, rules.allowed(tc.path))
+		})
+	}
+
+	t.Run("empty robots allows everything", func(t *testing.T) {
+		t.Parallel()
+		assert.True(t, parseRobots(nil, "urlresolver").allowed("/anything"))
+	})
+}
+
+func TestRobotsTxt(t *testing.T) {
+	t.Parallel()
+
+	newServer := func(robotsStatus int, robotsFetches *int64) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/robots.txt":
+				atomic.AddInt64(robotsFetches, 1)
+				w.WriteHeader(robotsStatus)
+				w.Write([]byte("User-agent: *\nDisallow: /private/\n")) //nolint:errcheck
+			case "/redirect":
+				http.Redirect(w, r, "/private/page", http.StatusFound)
+			default:
+				w.Write([]byte(`<title>title</title>`)) //nolint:errcheck
+			}
+		}))
+	}
+
+	testCases := map[string]struct {
+		robotsStatus int
+		path         string
+		want         Result
+	}{
+		"allowed": {
+			robotsStatus: http.StatusOK,
+			path:         "/public/page",
+			want: Result{
+				ResolvedURL: "/public/page",
+				Title:       "title",
+				Outcome:     OutcomeOK,
+				StatusCode:  200,
+			},
+		},
+		"disallowed after redirect": {
+			robotsStatus: http.StatusOK,
+			path:         "/redirect",
+			want: Result{
+				ResolvedURL:      "/private/page",
+				IntermediateURLs: []string{"/redirect"},
+				Outcome:          OutcomePartialNoTitle,
+				StatusCode:       200,
+				RobotsDisallowed: true,
+			},
+		},
+		"missing robots.txt allows everything": {
+			robotsStatus: http.StatusNotFound,
+			path:         "/private/page",
+			want: Result{
+				ResolvedURL: "/private/page",
+				Title:       "title",
+				Outcome:     OutcomeOK,
+				StatusCode:  200,
+			},
+		},
+		"forbidden robots.txt allows everything": {
+			robotsStatus: http.StatusForbidden,
+			path:         "/private/page",
+			want: Result{
+				ResolvedURL: "/private/page",
+				Title:       "title",
+				Outcome:     OutcomeOK,
+				StatusCode:  200,
+			},
+		},
+		"robots.txt server error disallows everything": {
+			robotsStatus: http.StatusServiceUnavailable,
+			path:         "/public/page",
+			want: Result{
+				ResolvedURL:      "/public/page",
+				Outcome:          OutcomePartialNoTitle,
+				StatusCode:       200,
+				RobotsDisallowed: true,
+			},
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var robotsFetches int64
+			srv := newServer(tc.robotsStatus, &robotsFetches)
+			defer srv.Close()
+
+			resolver := New(newSafeTestTransport(t), 0, WithRobotsTxt("urlresolver"))
+			result, err := resolver.Resolve(context.Background(), srv.URL+tc.path)
+			assert.NoError(t, err)
+
+			tc.want.ResolvedURL = renderURL(srv.URL, tc.want.ResolvedURL)
+			for idx, hop := range tc.want.IntermediateURLs {
+				tc.want.IntermediateURLs[idx] = renderURL(srv.URL, hop)
+			}
+			assert.Equal(t, tc.want, withoutHops(t, result))
+		})
+	}
+
+	t.Run("robots.txt is cached", func(t *testing.T) {
+		t.Parallel()
+
+		var robotsFetches int64
+		srv := newServer(http.StatusOK, &robotsFetches)
+		defer srv.Close()
+
+		resolver := New(newSafeTestTransport(t), 0, WithRobotsTxt("urlresolver"))
+		for _, path := range []string{"/a", "/b", "/private/c"} {
+			_, err := resolver.Resolve(context.Background(), srv.URL+path)
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, int64(1), atomic.LoadInt64(&robotsFetches))
+	})
+
+	t.Run("robots.txt server errors are cached briefly", func(t *testing.T) {
+		t.Parallel()
+
+		var robotsFetches int64
+		srv := newServer(http.StatusServiceUnavailable, &robotsFetches)
+		defer srv.Close()
+
+		resolver := New(newSafeTestTransport(t), 0, WithRobotsTxt("urlresolver"))
+		for _, path := range []string{"/a", "/b"} {
+			result, err := resolver.Resolve(context.Background(), srv.URL+path)
+			assert.NoError(t, err)
+			assert.True(t, result.RobotsDisallowed)
+		}
+		assert.Equal(t, int64(1), atomic.LoadInt64(&robotsFetches))
+
+		entry := resolver.robots.entries[srv.URL]
+		assert.WithinDuration(t, time.Now().Add(robotsErrorTTL), entry.expires, 5*time.Second)
+	})
+
+	t.Run("failed robots.txt fetches are not cached", func(t *testing.T) {
+		t.Parallel()
+
+		var robotsFetches int64
+		srv := newServer(http.StatusOK, &robotsFetches)
+		defer srv.Close()
+
+		// the first robots.txt fetch fails, as if it had run out of time
+		var failed atomic.Bool
+		transport := &testTransport{
+			roundTrip: func(r *http.Request) (*http.Response, error) {
+				if r.URL.Path == "/robots.txt" && failed.CompareAndSwap(false, true) {
+					return nil, context.DeadlineExceeded
+				}
+				return http.DefaultTransport.RoundTrip(r)
+			},
+		}
+
+		resolver := New(transport, 0, WithRobotsTxt("urlresolver"))
+		result, err := resolver.Resolve(context.Background(), srv.URL+"/a")
+		assert.NoError(t, err)
+		assert.False(t, result.RobotsDisallowed, "failed fetches are not disallows")
+		assert.Equal(t, "title", result.Title)
+
+		result, err = resolver.Resolve(context.Background(), srv.URL+"/b")
+		assert.NoError(t, err)
+		assert.False(t, result.RobotsDisallowed)
+		assert.Equal(t, "title", result.Title)
+		assert.Equal(t, int64(1), atomic.LoadInt64(&robotsFetches))
+	})
+
+	t.Run("per-host concurrency limit", func(t *testing.T) {
+		t.Parallel()
+
+		var robotsFetches int64
+		srv := newServer(http.StatusOK, &robotsFetches)
+		defer srv.Close()
+
+		// the final response holds the host's only slot while robots.txt is
+		// checked
+		resolver := New(newSafeTestTransport(t), 2*time.Second, WithRobotsTxt("urlresolver"), WithMaxConcurrencyPerHost(1))
+		start := time.Now()
+		result, err := resolver.Resolve(context.Background(), srv.URL+"/public/page")
+		assert.NoError(t, err)
+		assert.False(t, result.RobotsDisallowed)
+		assert.Equal(t, "title", result.Title)
+		assert.Equal(t, int64(1), atomic.LoadInt64(&robotsFetches))
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
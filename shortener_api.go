@@ -0,0 +1,169 @@
+package urlresolver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mccutchen/urlresolver/internal/urlutil"
+)
+
+// maxShortenerAPIResponseSize limits how much of a shortener API response we
+// will read.
+const maxShortenerAPIResponseSize = 64 * 1024
+
+// NewBitlyUnwrapper returns an Unwrapper that expands Bitly links (bit.ly,
+// j.mp, and any additional custom domains given) using the Bitly API and the
+// given access token.
+func NewBitlyUnwrapper(transport http.RoundTripper, token string, customDomains ...string) Unwrapper {
+	return &shortenerAPIUnwrapper{
+		name:       "bitly",
+		baseURL:    "https://api-ssl.bitly.com",
+		domains:    append([]string{"bit.ly", "j.mp"}, urlutil.NormalizeDomains(customDomains)...),
+		httpClient: &http.Client{Transport: transport, Timeout: defaultTimeout},
+		newRequest: func(ctx context.Context, baseURL string, u *url.URL) (*http.Request, error) {
+			body, _ := json.Marshal(map[string]string{"bitlink_id": u.Host + u.Path})
+			req, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/v4/expand", bytes.NewReader(body))
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+			req.Header.Set("Content-Type", "application/json")
+			return req, nil
+		},
+		parseResponse: func(body []byte) (string, error) {
+			var result struct {
+				LongURL string `json:"long_url"`
+			}
+			err := json.Unmarshal(body, &result)
+			return result.LongURL, err
+		},
+	}
+}
+
+// NewTinyURLUnwrapper returns an Unwrapper that expands TinyURL links
+// (tinyurl.com and any additional custom domains given) using the TinyURL API
+// and the given API token.
+func NewTinyURLUnwrapper(transport http.RoundTripper, token string, customDomains ...string) Unwrapper {
+	return &shortenerAPIUnwrapper{
+		name:       "tinyurl",
+		baseURL:    "https://api.tinyurl.com",
+		domains:    append([]string{"tinyurl.com"}, urlutil.NormalizeDomains(customDomains)...),
+		httpClient: &http.Client{Transport: transport, Timeout: defaultTimeout},
+		newRequest: func(ctx context.Context, baseURL string, u *url.URL) (*http.Request, error) {
+			alias := strings.Trim(u.Path, "/")
+			apiURL := fmt.Sprintf("%s/alias/%s/%s", baseURL, url.PathEscape(u.Hostname()), url.PathEscape(alias))
+			req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+			return req, nil
+		},
+		parseResponse: func(body []byte) (string, error) {
+			var result struct {
+				Data struct {
+					URL string `json:"url"`
+				} `json:"data"`
+			}
+			err := json.Unmarshal(body, &result)
+			return result.Data.URL, err
+		},
+	}
+}
+
+// NewRebrandlyUnwrapper returns an Unwrapper that expands Rebrandly links
+// (rebrand.ly and any additional custom domains given) using the Rebrandly
+// API and the given API key.
+func NewRebrandlyUnwrapper(transport http.RoundTripper, apiKey string, customDomains ...string) Unwrapper {
+	return &shortenerAPIUnwrapper{
+		name:       "rebrandly",
+		baseURL:    "https://api.rebrandly.com",
+		domains:    append([]string{"rebrand.ly"}, urlutil.NormalizeDomains(customDomains)...),
+		httpClient: &http.Client{Transport: transport, Timeout: defaultTimeout},
+		newRequest: func(ctx context.Context, baseURL string, u *url.URL) (*http.Request, error) {
+			params := url.Values{
+				"domain.fullName": []string{u.Hostname()},
+				"slashtag":        []string{strings.Trim(u.Path, "/")},
+			}
+			req, err := http.NewRequestWithContext(ctx, "GET", baseURL+"/v1/links?"+params.Encode(), nil)
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("apikey", apiKey)
+			return req, nil
+		},
+		parseResponse: func(body []byte) (string, error) {
+			var result []struct {
+				Destination string `json:"destination"`
+			}
+			if err := json.Unmarshal(body, &result); err != nil {
+				return "", err
+			}
+			if len(result) == 0 {
+				return "", nil
+			}
+			return result[0].Destination, nil
+		},
+	}
+}
+
+// shortenerAPIUnwrapper is an Unwrapper that expands links on a set of
+// domains by making a request to a URL shortener's API.
+type shortenerAPIUnwrapper struct {
+	name          string
+	baseURL       string
+	domains       []string
+	httpClient    *http.Client
+	newRequest    func(ctx context.Context, baseURL string, u *url.URL) (*http.Request, error)
+	parseResponse func(body []byte) (string, error)
+}
+
+// Unwrap implements Unwrapper.
+func (s *shortenerAPIUnwrapper) Unwrap(ctx context.Context, u *url.URL) (*url.URL, bool) {
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if !urlutil.MatchDomains(host, s.domains) || strings.Trim(u.Path, "/") == "" {
+		return nil, false
+	}
+	target, err := s.expand(ctx, u)
+	if err != nil {
+		return nil, false
+	}
+	return target, true
+}
+
+func (s *shortenerAPIUnwrapper) expand(ctx context.Context, u *url.URL) (*url.URL, error) {
+	req, err := s.newRequest(ctx, s.baseURL, u)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s api error: HTTP %d", s.name, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxShortenerAPIResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s api response: %w", s.name, err)
+	}
+	longURL, err := s.parseResponse(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid json in %s api response: %w", s.name, err)
+	}
+
+	target, err := url.Parse(longURL)
+	if err != nil || target.Host == "" || (target.Scheme != "http" && target.Scheme != "https") {
+		return nil, fmt.Errorf("unexpected url in %s api response: %q", s.name, longURL)
+	}
+	return target, nil
+}
@@ -0,0 +1,165 @@
+package urlresolver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShortenerAPIUnwrappers(t *testing.T) {
+	t.Parallel()
+
+	const longURL = "https://example.com/article"
+
+	testCases := map[string]struct {
+		newUnwrapper func(transport http.RoundTripper) Unwrapper
+		givenURL     string
+		handler      http.HandlerFunc
+		wantOK       bool
+	}{
+		"bitly": {
+			newUnwrapper: func(transport http.RoundTripper) Unwrapper {
+				return NewBitlyUnwrapper(transport, "token")
+			},
+			givenURL: "https://bit.ly/abc123",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				var body map[string]string
+				json.NewDecoder(r.Body).Decode(&body) //nolint:errcheck
+				assert.Equal(t, "POST", r.Method)
+				assert.Equal(t, "/v4/expand", r.URL.Path)
+				assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+				assert.Equal(t, map[string]string{"bitlink_id": "bit.ly/abc123"}, body)
+				w.Write([]byte(`{"long_url": "` + longURL + `"}`)) //nolint:errcheck
+			},
+			wantOK: true,
+		},
+		"bitly custom domain": {
+			newUnwrapper: func(transport http.RoundTripper) Unwrapper {
+				return NewBitlyUnwrapper(transport, "token", "Links.Example.org")
+			},
+			givenURL: "https://links.example.org/abc123",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"long_url": "` + longURL + `"}`)) //nolint:errcheck
+			},
+			wantOK: true,
+		},
+		"tinyurl": {
+			newUnwrapper: func(transport http.RoundTripper) Unwrapper {
+				return NewTinyURLUnwrapper(transport, "token")
+			},
+			givenURL: "https://tinyurl.com/abc123",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "GET", r.Method)
+				assert.Equal(t, "/alias/tinyurl.com/abc123", r.URL.Path)
+				assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+				w.Write([]byte(`{"data": {"url": "` + longURL + `"}}`)) //nolint:errcheck
+			},
+			wantOK: true,
+		},
+		"rebrandly": {
+			newUnwrapper: func(transport http.RoundTripper) Unwrapper {
+				return NewRebrandlyUnwrapper(transport, "key")
+			},
+			givenURL: "https://rebrand.ly/abc123",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/v1/links", r.URL.Path)
+				assert.Equal(t, "rebrand.ly", r.URL.Query().Get("domain.fullName"))
+				assert.Equal(t, "abc123", r.URL.Query().Get("slashtag"))
+				assert.Equal(t, "key", r.Header.Get("apikey"))
+				w.Write([]byte(`[{"destination": "` + longURL + `"}]`)) //nolint:errcheck
+			},
+			wantOK: true,
+		},
+		"rebrandly link not found": {
+			newUnwrapper: func(transport http.RoundTripper) Unwrapper {
+				return NewRebrandlyUnwrapper(transport, "key")
+			},
+			givenURL: "https://rebrand.ly/abc123",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`[]`)) //nolint:errcheck
+			},
+		},
+		"other domains ignored": {
+			newUnwrapper: func(transport http.RoundTripper) Unwrapper {
+				return NewBitlyUnwrapper(transport, "token")
+			},
+			givenURL: "https://notbit.ly/abc123",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				t.Errorf("unexpected api request")
+			},
+		},
+		"api error": {
+			newUnwrapper: func(transport http.RoundTripper) Unwrapper {
+				return NewBitlyUnwrapper(transport, "token")
+			},
+			givenURL: "https://bit.ly/abc123",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusTooManyRequests)
+			},
+		},
+		"invalid long url": {
+			newUnwrapper: func(transport http.RoundTripper) Unwrapper {
+				return NewBitlyUnwrapper(transport, "token")
+			},
+			givenURL: "https://bit.ly/abc123",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"long_url": "javascript:alert(1)"}`)) //nolint:errcheck
+			},
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewServer(tc.handler)
+			defer srv.Close()
+
+			unwrapper := tc.newUnwrapper(newSafeTestTransport(t))
+			unwrapper.(*shortenerAPIUnwrapper).baseURL = srv.URL
+
+			given, _ := url.Parse(tc.givenURL)
+			target, ok := unwrapper.Unwrap(context.Background(), given)
+			assert.Equal(t, tc.wantOK, ok)
+			if tc.wantOK {
+				assert.Equal(t, longURL, target.String())
+			}
+		})
+	}
+}
+
+func TestResolveWithUnwrappers(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v4/expand":
+			w.Write([]byte(`{"long_url": "http://` + r.Host + `/article"}`)) //nolint:errcheck
+		case "/article":
+			w.Write([]byte(`<title>article</title>`)) //nolint:errcheck
+		default:
+			t.Errorf("unexpected request to %s", r.URL)
+		}
+	}))
+	defer srv.Close()
+
+	unwrapper := NewBitlyUnwrapper(newSafeTestTransport(t), "token")
+	unwrapper.(*shortenerAPIUnwrapper).baseURL = srv.URL
+
+	resolver := New(newSafeTestTransport(t), 0, WithUnwrappers(unwrapper))
+	result, err := resolver.Resolve(context.Background(), "https://bit.ly/abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, Result{
+		ResolvedURL:      srv.URL + "/article",
+		Title:            "article",
+		IntermediateURLs: []string{"https://bit.ly/abc123"},
+		Hops:             []Hop{{URL: "https://bit.ly/abc123", Kind: HopUnwrap}},
+		Outcome:          OutcomeOK,
+		StatusCode:       200,
+	}, result)
+}
@@ -0,0 +1,80 @@
+package urlresolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// ErrNoStaticResult is returned when a StaticResolver without a fallback is
+// asked to resolve a URL it has no result for.
+var ErrNoStaticResult = errors.New("no static result")
+
+// StaticResolver resolves URLs from a preloaded map of results, optionally
+// falling through to another resolver for any URL not in the map. It makes
+// no network requests of its own, which makes it useful for offline
+// development, demos, and deterministic integration tests.
+type StaticResolver struct {
+	results  map[string]Result
+	fallback Interface
+}
+
+var (
+	_ Interface         = &StaticResolver{} // StaticResolver implements Interface
+	_ DetailedInterface = &StaticResolver{} // StaticResolver implements DetailedInterface
+)
+
+// NewStaticResolver creates a StaticResolver that returns the given results,
+// which are keyed by URL. A URL is looked up as given and then in its
+// canonical form, so keys may be either. URLs not found in results are
+// resolved by fallback, if it is not nil.
+func NewStaticResolver(results map[string]Result, fallback Interface) *StaticResolver {
+	s := &StaticResolver{
+		results:  make(map[string]Result, len(results)),
+		fallback: fallback,
+	}
+	for givenURL, result := range results {
+		s.results[givenURL] = result
+	}
+	return s
+}
+
+// Resolve returns the preloaded result for the given URL, falling through to
+// the fallback resolver if there is none.
+func (s *StaticResolver) Resolve(ctx context.Context, givenURL string) (Result, error) {
+	if result, ok := s.lookup(givenURL); ok {
+		return result, nil
+	}
+	if s.fallback == nil {
+		return Result{ResolvedURL: givenURL, Outcome: OutcomeError}, fmt.Errorf("%w: %s", ErrNoStaticResult, givenURL)
+	}
+	return s.fallback.Resolve(ctx, givenURL)
+}
+
+// ResolveDetailed returns the preloaded result for the given URL, which has
+// no extra details, falling through to the fallback resolver's detailed form
+// if there is none.
+func (s *StaticResolver) ResolveDetailed(ctx context.Context, givenURL string) (DetailedResult, error) {
+	if result, ok := s.lookup(givenURL); ok {
+		return DetailedResult{Result: result}, nil
+	}
+	if s.fallback == nil {
+		return DetailedResult{Result: Result{ResolvedURL: givenURL, Outcome: OutcomeError}}, fmt.Errorf("%w: %s", ErrNoStaticResult, givenURL)
+	}
+	return ResolveDetailed(ctx, s.fallback, givenURL)
+}
+
+// lookup finds the preloaded result for the given URL, trying its canonical
+// form if it isn't found as given.
+func (s *StaticResolver) lookup(givenURL string) (Result, bool) {
+	if result, ok := s.results[givenURL]; ok {
+		return result, true
+	}
+	u, err := url.Parse(givenURL)
+	if err != nil {
+		return Result{}, false
+	}
+	result, ok := s.results[Canonicalize(u)]
+	return result, ok
+}
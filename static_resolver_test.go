@@ -0,0 +1,102 @@
+package urlresolver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaticResolver(t *testing.T) {
+	t.Parallel()
+
+	results := map[string]Result{
+		"https://example.com/static": {
+			ResolvedURL: "https://example.com/static",
+			Title:       "Static",
+			Outcome:     OutcomeOK,
+		},
+		"https://example.com/canonical": {
+			ResolvedURL: "https://example.com/canonical",
+			Title:       "Canonical",
+			Outcome:     OutcomeOK,
+		},
+	}
+
+	t.Run("without fallback", func(t *testing.T) {
+		t.Parallel()
+
+		resolver := NewStaticResolver(results, nil)
+		testCases := map[string]struct {
+			givenURL string
+			want     Result
+			wantErr  error
+		}{
+			"exact match": {
+				givenURL: "https://example.com/static",
+				want:     results["https://example.com/static"],
+			},
+			"canonical match": {
+				givenURL: "HTTPS://Example.com/canonical?utm_source=foo#bar",
+				want:     results["https://example.com/canonical"],
+			},
+			"no match": {
+				givenURL: "https://example.com/missing",
+				want:     Result{ResolvedURL: "https://example.com/missing", Outcome: OutcomeError},
+				wantErr:  ErrNoStaticResult,
+			},
+		}
+		for name, tc := range testCases {
+			tc := tc
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+
+				result, err := resolver.Resolve(context.Background(), tc.givenURL)
+				assert.ErrorIs(t, err, tc.wantErr)
+				assert.Equal(t, tc.want, result)
+
+				detailed, err := resolver.ResolveDetailed(context.Background(), tc.givenURL)
+				assert.ErrorIs(t, err, tc.wantErr)
+				assert.Equal(t, DetailedResult{Result: tc.want}, detailed)
+			})
+		}
+	})
+
+	t.Run("with fallback", func(t *testing.T) {
+		t.Parallel()
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<title>Live</title><meta name="description" content="Live page">`)) //nolint:errcheck
+		}))
+		defer srv.Close()
+
+		resolver := NewStaticResolver(results, New(newSafeTestTransport(t), 0))
+
+		result, err := resolver.Resolve(context.Background(), "https://example.com/static")
+		assert.NoError(t, err)
+		assert.Equal(t, "Static", result.Title)
+
+		result, err = resolver.Resolve(context.Background(), srv.URL+"/live")
+		assert.NoError(t, err)
+		assert.Equal(t, "Live", result.Title)
+
+		detailed, err := resolver.ResolveDetailed(context.Background(), srv.URL+"/live")
+		assert.NoError(t, err)
+		assert.Equal(t, "Live page", detailed.Metadata.Description)
+	})
+
+	t.Run("results are copied", func(t *testing.T) {
+		t.Parallel()
+
+		given := map[string]Result{"https://example.com/": {Title: "Before"}}
+		resolver := NewStaticResolver(given, nil)
+		given["https://example.com/"] = Result{Title: "After"}
+
+		result, err := resolver.Resolve(context.Background(), "https://example.com/")
+		assert.NoError(t, err)
+		assert.Equal(t, "Before", result.Title)
+	})
+}
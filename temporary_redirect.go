@@ -0,0 +1,52 @@
+package urlresolver
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// TemporaryRedirectPolicy is called when a redirect chain ends with a
+// temporary (302, 303, or 307) redirect from one URL to another. If it
+// returns true, the URL that redirected is used as the resolved URL instead
+// of the redirect's target.
+//
+// This is useful for sites that temporarily redirect to geo- or
+// session-specific editions of a page, which should not be treated as the
+// canonical URL for the page.
+type TemporaryRedirectPolicy func(from *url.URL, to *url.URL) bool
+
+// WithTemporaryRedirectPolicy sets a TemporaryRedirectPolicy. The title is
+// still taken from the redirect's target.
+func WithTemporaryRedirectPolicy(policy TemporaryRedirectPolicy) Option {
+	return func(r *Resolver) {
+		r.temporaryRedirectPolicy = policy
+	}
+}
+
+// isPermanentRedirect returns true if the given status code indicates a
+// permanent redirect.
+func isPermanentRedirect(statusCode int) bool {
+	return statusCode == http.StatusMovedPermanently || statusCode == http.StatusPermanentRedirect
+}
+
+// applyTemporaryRedirectPolicy rewinds the result to the last intermediate
+// hop if the chain ends in a temporary redirect that the policy rejects.
+func (r *Resolver) applyTemporaryRedirectPolicy(result *Result, target *url.URL) {
+	if r.temporaryRedirectPolicy == nil || len(result.Hops) == 0 {
+		return
+	}
+	last := result.Hops[len(result.Hops)-1]
+	if last.Kind != HopRedirect || isPermanentRedirect(last.StatusCode) {
+		return
+	}
+	from, err := url.Parse(last.URL)
+	if err != nil || !r.temporaryRedirectPolicy(from, target) {
+		return
+	}
+	result.ResolvedURL = r.canonicalize(from)
+	result.IntermediateURLs = result.IntermediateURLs[:len(result.IntermediateURLs)-1]
+	result.Hops = result.Hops[:len(result.Hops)-1]
+	if len(result.Hops) == 0 {
+		result.IntermediateURLs, result.Hops = nil, nil
+	}
+}
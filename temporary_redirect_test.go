@@ -0,0 +1,103 @@
+package urlresolver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemporaryRedirectPolicy(t *testing.T) {
+	t.Parallel()
+
+	// keep the original URL when redirected to a regional edition
+	regionalEdition := func(from *url.URL, to *url.URL) bool {
+		return strings.HasPrefix(to.Path, "/edition/")
+	}
+
+	testCases := map[string]struct {
+		status     int
+		policy     TemporaryRedirectPolicy
+		wantResult Result
+	}{
+		"no policy": {
+			status: http.StatusFound,
+			wantResult: Result{
+				ResolvedURL:      "/edition/uk/article",
+				Title:            "article",
+				IntermediateURLs: []string{"/short", "/article"},
+				Outcome:          OutcomeOK,
+				StatusCode:       200,
+			},
+		},
+		"temporary redirect kept by policy": {
+			status: http.StatusFound,
+			policy: regionalEdition,
+			wantResult: Result{
+				ResolvedURL:      "/article",
+				Title:            "article",
+				IntermediateURLs: []string{"/short"},
+				Outcome:          OutcomeOK,
+				StatusCode:       200,
+			},
+		},
+		"temporary redirect allowed by policy": {
+			status: http.StatusTemporaryRedirect,
+			policy: func(from *url.URL, to *url.URL) bool { return false },
+			wantResult: Result{
+				ResolvedURL:      "/edition/uk/article",
+				Title:            "article",
+				IntermediateURLs: []string{"/short", "/article"},
+				Outcome:          OutcomeOK,
+				StatusCode:       200,
+			},
+		},
+		"permanent redirect ignores policy": {
+			status: http.StatusMovedPermanently,
+			policy: regionalEdition,
+			wantResult: Result{
+				ResolvedURL:      "/edition/uk/article",
+				Title:            "article",
+				IntermediateURLs: []string{"/short", "/article"},
+				Outcome:          OutcomeOK,
+				StatusCode:       200,
+			},
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/short":
+					http.Redirect(w, r, "/article", http.StatusMovedPermanently)
+				case "/article":
+					http.Redirect(w, r, "/edition/uk/article", tc.status)
+				default:
+					w.Write([]byte("<title>article</title>")) //nolint:errcheck
+				}
+			}))
+			defer srv.Close()
+
+			var opts []Option
+			if tc.policy != nil {
+				opts = append(opts, WithTemporaryRedirectPolicy(tc.policy))
+			}
+			resolver := New(newSafeTestTransport(t), 0, opts...)
+			result, err := resolver.Resolve(context.Background(), srv.URL+"/short")
+			assert.NoError(t, err)
+
+			tc.wantResult.ResolvedURL = srv.URL + tc.wantResult.ResolvedURL
+			for i, u := range tc.wantResult.IntermediateURLs {
+				tc.wantResult.IntermediateURLs[i] = srv.URL + u
+			}
+			assert.Equal(t, tc.wantResult, withoutHops(t, result))
+		})
+	}
+}
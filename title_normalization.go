@@ -0,0 +1,132 @@
+package urlresolver
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// titleNormalizer post-processes titles according to the resolver's options.
+type titleNormalizer struct {
+	collapseWhitespace bool
+	stripSiteNames     bool
+	siteNames          []string
+	maxLength          int
+}
+
+// WithTitleWhitespaceCollapsing makes the resolver collapse each run of
+// whitespace within a title, including newlines and non-breaking spaces,
+// into a single space.
+func WithTitleWhitespaceCollapsing() Option {
+	return func(r *Resolver) {
+		r.titleNormalizer.collapseWhitespace = true
+	}
+}
+
+// WithTitleSiteNameStripping makes the resolver strip a trailing site name
+// from titles, as in "Some headline — The New York Times" or "Some video -
+// YouTube".
+//
+// A suffix following a common separator (-, –, —, |, ·, or •) is stripped if
+// it matches one of the given site names or, ignoring case, spaces, and
+// punctuation, the name of the resolved URL's domain. Matching the domain
+// handles most sites, so site names need only be given for those whose
+// names differ from their domains (e.g. "The New York Times" for
+// nytimes.com).
+func WithTitleSiteNameStripping(siteNames ...string) Option {
+	return func(r *Resolver) {
+		r.titleNormalizer.stripSiteNames = true
+		r.titleNormalizer.siteNames = append(r.titleNormalizer.siteNames, siteNames...)
+	}
+}
+
+// WithMaxTitleLength makes the resolver truncate titles longer than n runes,
+// ending them with an ellipsis so that the result is still n runes long.
+// Values less than 1 are ignored.
+func WithMaxTitleLength(n int) Option {
+	return func(r *Resolver) {
+		if n > 0 {
+			r.titleNormalizer.maxLength = n
+		}
+	}
+}
+
+// titleSeparators separate a page's own title from the site's name.
+var titleSeparators = []string{" - ", " – ", " — ", " | ", " · ", " • "}
+
+// normalize applies any configured post-processing to the given title,
+// which was found at a URL with the given host.
+func (n titleNormalizer) normalize(title string, host string) string {
+	if n.collapseWhitespace {
+		title = strings.Join(strings.Fields(title), " ")
+	}
+	if n.stripSiteNames {
+		title = n.stripSiteName(title, host)
+	}
+	if n.maxLength > 0 {
+		title = truncateTitle(title, n.maxLength)
+	}
+	return title
+}
+
+// stripSiteName removes a trailing site name from the given title, unless
+// that would leave nothing behind.
+func (n titleNormalizer) stripSiteName(title string, host string) string {
+	idx, sepLen := -1, 0
+	for _, sep := range titleSeparators {
+		if i := strings.LastIndex(title, sep); i > idx {
+			idx, sepLen = i, len(sep)
+		}
+	}
+	if idx <= 0 {
+		return title
+	}
+
+	head, suffix := strings.TrimSpace(title[:idx]), strings.TrimSpace(title[idx+sepLen:])
+	if head == "" || !n.isSiteName(suffix, host) {
+		return title
+	}
+	return head
+}
+
+// isSiteName returns true if the given title suffix names the site at the
+// given host.
+func (n titleNormalizer) isSiteName(suffix string, host string) bool {
+	for _, name := range n.siteNames {
+		if strings.EqualFold(suffix, name) {
+			return true
+		}
+	}
+	if host == "" {
+		return false
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return false
+	}
+	name, _, _ := strings.Cut(domain, ".")
+	return name != "" && squashName(suffix) == squashName(name)
+}
+
+// squashName lowercases the given name and drops anything other than letters
+// and digits, so that e.g. "The Verge" matches theverge.com.
+func squashName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
+
+// truncateTitle truncates the given title to at most maxLength runes,
+// including a trailing ellipsis if any truncation was needed.
+func truncateTitle(title string, maxLength int) string {
+	if utf8.RuneCountInString(title) <= maxLength {
+		return title
+	}
+	runes := []rune(title)[:maxLength-1]
+	return strings.TrimRightFunc(string(runes), unicode.IsSpace) + "…"
+}
@@ -0,0 +1,128 @@
+package urlresolver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTitleNormalization(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		normalizer titleNormalizer
+		title      string
+		host       string
+		want       string
+	}{
+		"no normalization": {
+			title: "  A \n messy   title - YouTube  ",
+			host:  "www.youtube.com",
+			want:  "  A \n messy   title - YouTube  ",
+		},
+		"whitespace collapsed": {
+			normalizer: titleNormalizer{collapseWhitespace: true},
+			title:      "  A \n\t messy  title  ",
+			want:       "A messy title",
+		},
+		"site name matching domain stripped": {
+			normalizer: titleNormalizer{stripSiteNames: true},
+			title:      "Some video - YouTube",
+			host:       "www.youtube.com",
+			want:       "Some video",
+		},
+		"multi-word site name matching domain stripped": {
+			normalizer: titleNormalizer{stripSiteNames: true},
+			title:      "A review | The Verge",
+			host:       "theverge.com",
+			want:       "A review",
+		},
+		"given site name stripped": {
+			normalizer: titleNormalizer{stripSiteNames: true, siteNames: []string{"The New York Times"}},
+			title:      "Some headline — the new york times",
+			host:       "www.nytimes.com",
+			want:       "Some headline",
+		},
+		"only last segment stripped": {
+			normalizer: titleNormalizer{stripSiteNames: true},
+			title:      "Issue #1 · owner/repo · GitHub",
+			host:       "github.com",
+			want:       "Issue #1 · owner/repo",
+		},
+		"unknown suffix kept": {
+			normalizer: titleNormalizer{stripSiteNames: true},
+			title:      "Spider-Man - Into the Spider-Verse",
+			host:       "example.com",
+			want:       "Spider-Man - Into the Spider-Verse",
+		},
+		"bare site name kept": {
+			normalizer: titleNormalizer{stripSiteNames: true},
+			title:      " - YouTube",
+			host:       "youtube.com",
+			want:       " - YouTube",
+		},
+		"no host": {
+			normalizer: titleNormalizer{stripSiteNames: true},
+			title:      "Some video - YouTube",
+			want:       "Some video - YouTube",
+		},
+		"short title not truncated": {
+			normalizer: titleNormalizer{maxLength: 10},
+			title:      "Iñtërnâtiô",
+			want:       "Iñtërnâtiô",
+		},
+		"long title truncated by runes": {
+			normalizer: titleNormalizer{maxLength: 10},
+			title:      "Iñtërnâtiônàlizætiøn",
+			want:       "Iñtërnâti…",
+		},
+		"trailing space trimmed before ellipsis": {
+			normalizer: titleNormalizer{maxLength: 8},
+			title:      "A title that is too long",
+			want:       "A title…",
+		},
+		"all combined": {
+			normalizer: titleNormalizer{collapseWhitespace: true, stripSiteNames: true, maxLength: 22},
+			title:      "\n  A rather   long headline\n  | Example News\n",
+			host:       "www.examplenews.com",
+			want:       "A rather long headline",
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, tc.normalizer.normalize(tc.title, tc.host))
+		})
+	}
+}
+
+func TestTitleNormalizationOptions(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<title>\n  A   headline that goes on\n  - The Daily Example\n</title>")) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	var hookTitle string
+	resolver := New(newSafeTestTransport(t), 0,
+		WithTitleWhitespaceCollapsing(),
+		WithTitleSiteNameStripping("The Daily Example"),
+		WithMaxTitleLength(20),
+		WithMaxTitleLength(0), // ignored
+		WithHooks(Hooks{
+			OnTitleParsed: func(_ context.Context, _ string, title string) {
+				hookTitle = title
+			},
+		}),
+	)
+	result, err := resolver.Resolve(context.Background(), srv.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, "A headline that goe…", result.Title)
+	assert.Equal(t, result.Title, hookTitle)
+}
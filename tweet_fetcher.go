@@ -16,17 +16,29 @@ import (
 	"github.com/mccutchen/urlresolver/bufferpool"
 )
 
-// tweetFetcher fetches tweets
-type tweetFetcher interface {
-	Fetch(ctx context.Context, tweetURL string) (tweetData, error)
+// TweetFetcher fetches tweets, which a Resolver asks for instead of
+// fetching tweet URLs directly. By default, tweets are fetched from
+// Twitter's oembed endpoint.
+type TweetFetcher interface {
+	Fetch(ctx context.Context, tweetURL string) (Tweet, error)
 }
 
-// tweetData is a minimal representation of a tweet's data
-type tweetData struct {
+// Tweet is a minimal representation of a tweet's data. Its Text is used as
+// the title of the resolved tweet URL.
+type Tweet struct {
 	URL  string
 	Text string
 }
 
+// WithTweetFetcher replaces the fetcher used to resolve tweet URLs, e.g. with
+// an authenticated Twitter/X API client. A nil fetcher disables special
+// handling of tweet URLs, which are then resolved like any other URL.
+func WithTweetFetcher(f TweetFetcher) Option {
+	return func(r *Resolver) {
+		r.tweetFetcher = f
+	}
+}
+
 // oembedTweetFetcher knows how to fetch information about a tweet from Twitter's
 // oembed endpoint.
 type oembedTweetFetcher struct {
@@ -49,7 +61,7 @@ func newTweetFetcher(transport http.RoundTripper, timeout time.Duration, pool *b
 
 // Fetch returns the title and resolved URL for a tweet by fetching its
 // metadata from Twitter's oembed endpoint.
-func (f *oembedTweetFetcher) Fetch(ctx context.Context, tweetURL string) (tweetData, error) {
+func (f *oembedTweetFetcher) Fetch(ctx context.Context, tweetURL string) (Tweet, error) {
 	params := url.Values{
 		"url": []string{tweetURL},
 	}
@@ -58,17 +70,17 @@ func (f *oembedTweetFetcher) Fetch(ctx context.Context, tweetURL string) (tweetD
 	req, _ := http.NewRequestWithContext(ctx, "GET", oembedURL, nil)
 	resp, err := f.httpClient.Do(req)
 	if err != nil {
-		return tweetData{}, err
+		return Tweet{}, err
 	}
 	if resp.StatusCode != http.StatusOK {
-		return tweetData{}, fmt.Errorf("twitter oembed error: GET %s: HTTP %d", oembedURL, resp.StatusCode)
+		return Tweet{}, fmt.Errorf("twitter oembed error: GET %s: HTTP %d", oembedURL, resp.StatusCode)
 	}
 
 	buf := f.pool.Get()
 	defer f.pool.Put(buf)
 
 	if _, err := io.Copy(buf, resp.Body); err != nil {
-		return tweetData{}, fmt.Errorf("error reading twitter oembed response: %w", err)
+		return Tweet{}, fmt.Errorf("error reading twitter oembed response: %w", err)
 	}
 
 	var oembedResult struct {
@@ -77,14 +89,14 @@ func (f *oembedTweetFetcher) Fetch(ctx context.Context, tweetURL string) (tweetD
 		URL        string `json:"url"`
 	}
 	if err := json.Unmarshal(buf.Bytes(), &oembedResult); err != nil {
-		return tweetData{}, fmt.Errorf("invalid json in twitter oembed response: %w", err)
+		return Tweet{}, fmt.Errorf("invalid json in twitter oembed response: %w", err)
 	}
 
 	if oembedResult.URL == "" || oembedResult.HTML == "" {
-		return tweetData{}, fmt.Errorf("unexpected json format in twitter oembed response: %q", buf.String())
+		return Tweet{}, fmt.Errorf("unexpected json format in twitter oembed response: %q", buf.String())
 	}
 
-	return tweetData{
+	return Tweet{
 		URL:  oembedResult.URL,
 		Text: extractTweetText(oembedResult.HTML),
 	}, nil
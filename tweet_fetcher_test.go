@@ -96,7 +96,7 @@ func TestFetch(t *testing.T) {
 	testCases := map[string]struct {
 		handler    func(*testing.T) http.HandlerFunc
 		timeout    time.Duration
-		wantResult tweetData
+		wantResult Tweet
 		wantErr    error
 	}{
 		"ok": {
@@ -121,7 +121,7 @@ func TestFetch(t *testing.T) {
 `))
 				}
 			},
-			wantResult: tweetData{
+			wantResult: Tweet{
 				Text: "Hi. As the year draws to a close, I just wanted to apologize for (probably) turning into a firehouse of bad news aimed directly into your inbox. Rest assured, those responsible have been sacked. pic.twitter.com/o6S0p7s3Ce",
 				URL:  tweetURL,
 			},
@@ -193,7 +193,7 @@ func TestFetch(t *testing.T) {
 `))
 				}
 			},
-			wantResult: tweetData{
+			wantResult: Tweet{
 				Text: "",
 				URL:  tweetURL,
 			},
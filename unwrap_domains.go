@@ -0,0 +1,40 @@
+package urlresolver
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/mccutchen/urlresolver/internal/urlutil"
+)
+
+// WithUnwrapDomains restricts the resolver to unwrapping links on the given
+// domains (or any of their subdomains), e.g. URL shorteners and click
+// trackers. Redirects are followed only while the chain stays on these
+// domains, and the first URL outside of them is used as the resolved URL
+// without being fetched, so no title is extracted for it.
+//
+// This is useful for privacy-sensitive deployments that only want to expand
+// short links, without making requests to the sites they point to.
+func WithUnwrapDomains(domains ...string) Option {
+	return func(r *Resolver) {
+		r.unwrapDomains = append(r.unwrapDomains, urlutil.NormalizeDomains(domains)...)
+	}
+}
+
+// errLeftUnwrapDomains signals that resolution stopped at a URL outside of
+// the resolver's unwrap domains.
+var errLeftUnwrapDomains = errors.New("left unwrap domains")
+
+// checkUnwrapDomains returns errLeftUnwrapDomains if the resolver is limited
+// to unwrap domains and the given URL is not on one of them.
+func (r *Resolver) checkUnwrapDomains(u *url.URL) error {
+	if len(r.unwrapDomains) == 0 {
+		return nil
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if !urlutil.MatchDomains(host, r.unwrapDomains) {
+		return errLeftUnwrapDomains
+	}
+	return nil
+}
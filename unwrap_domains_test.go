@@ -0,0 +1,68 @@
+package urlresolver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnwrapDomains(t *testing.T) {
+	t.Parallel()
+
+	// short.link redirects through a click tracker to an article, which
+	// must never be fetched
+	newTransport := func(t *testing.T) *testTransport {
+		return &testTransport{
+			roundTrip: func(r *http.Request) (*http.Response, error) {
+				rec := httptest.NewRecorder()
+				switch r.URL.Host {
+				case "short.link":
+					http.Redirect(rec, r, "https://click.tracker.com/c?id=1", http.StatusFound)
+				case "click.tracker.com":
+					http.Redirect(rec, r, "https://news.example.com/article?utm_source=tracker", http.StatusFound)
+				default:
+					t.Errorf("unexpected request to %q", r.URL)
+					rec.WriteString("<title>article</title>") //nolint:errcheck
+				}
+				resp := rec.Result()
+				resp.Request = r
+				return resp, nil
+			},
+		}
+	}
+
+	testCases := map[string]struct {
+		givenURL   string
+		wantResult Result
+	}{
+		"stops at first third-party URL": {
+			givenURL: "https://short.link/abc",
+			wantResult: Result{
+				ResolvedURL:      "https://news.example.com/article",
+				IntermediateURLs: []string{"https://short.link/abc", "https://click.tracker.com/c?id=1"},
+				Outcome:          OutcomePartialNoTitle,
+			},
+		},
+		"third-party given URL is not fetched": {
+			givenURL: "https://news.example.com/article?utm_source=foo",
+			wantResult: Result{
+				ResolvedURL: "https://news.example.com/article",
+				Outcome:     OutcomePartialNoTitle,
+			},
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			resolver := New(newTransport(t), 0, WithUnwrapDomains("short.link", "tracker.com"))
+			result, err := resolver.Resolve(context.Background(), tc.givenURL)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantResult, withoutHops(t, result))
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package urlresolver
+
+import (
+	"context"
+	"net/url"
+)
+
+// Unwrapper expands wrapped or shortened URLs without fetching them, e.g. by
+// decoding the URL itself or by asking a URL shortener's API.
+type Unwrapper interface {
+	// Unwrap returns the URL that the given URL points to. If the given URL
+	// is not one the Unwrapper knows how to expand, or if expanding it fails,
+	// ok is false and the resolver will fetch the URL as usual.
+	Unwrap(ctx context.Context, u *url.URL) (target *url.URL, ok bool)
+}
+
+// WithUnwrappers adds one or more Unwrappers, which are applied in order to
+// the given URL before any requests are made. Each successful unwrap is
+// recorded as a hop of kind HopUnwrap.
+func WithUnwrappers(unwrappers ...Unwrapper) Option {
+	return func(r *Resolver) {
+		r.unwrappers = append(r.unwrappers, unwrappers...)
+	}
+}
+
+// unwrap applies the resolver's Unwrappers to the given URL, recording each
+// successful unwrap in the result, and returns the URL that should be
+// fetched. If resolution is aborted by the resolver's policies or a hook,
+// the URL that was rejected is returned along with the error.
+func (r *Resolver) unwrap(ctx context.Context, givenURL string, result *Result) (string, error) {
+	if len(r.unwrappers) == 0 {
+		return givenURL, nil
+	}
+	u, err := url.Parse(givenURL)
+	if err != nil {
+		return givenURL, nil
+	}
+	for _, unwrapper := range r.unwrappers {
+		target, ok := unwrapper.Unwrap(ctx, u)
+		if !ok {
+			continue
+		}
+		hop := Hop{URL: u.String(), Kind: HopUnwrap}
+		result.addHop(hop)
+		if err := r.checkUnwrapped(ctx, hop, target); err != nil {
+			return target.String(), err
+		}
+		u = target
+	}
+	return u.String(), nil
+}
+
+// checkUnwrapped returns an error if the target of the given unwrap hop is
+// disallowed by the resolver's policies or rejected by a hook.
+func (r *Resolver) checkUnwrapped(ctx context.Context, hop Hop, target *url.URL) error {
+	if err := r.checkURL(target); err != nil {
+		return err
+	}
+	return r.onUnwrap(ctx, hop, target)
+}
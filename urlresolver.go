@@ -2,11 +2,13 @@ package urlresolver
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
-	"net/http/cookiejar"
 	"net/url"
 	"regexp"
 	"strings"
@@ -14,18 +16,26 @@ import (
 
 	"golang.org/x/net/html"
 	"golang.org/x/net/html/charset"
-	"golang.org/x/net/publicsuffix"
-	"golang.org/x/sync/singleflight"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/unicode"
 
 	"github.com/mccutchen/urlresolver/bufferpool"
+	"github.com/mccutchen/urlresolver/canonical"
 )
 
 const (
-	defaultTimeout = 5 * time.Second
-	maxRedirects   = 5
-	maxBodySize    = 500 * 1024 // we'll read 500kb of body to find title
+	defaultTimeout      = 5 * time.Second
+	defaultMaxRedirects = 5
+	defaultMaxBodySize  = 500 * 1024 // we'll read 500kb of body to find title
+
+	// ... or more if we haven't found it yet, up to this cap
+	defaultMaxTitleSearchSize = 4 * 1024 * 1024
 )
 
+// ErrUnsupportedScheme is returned when a URL, or the target of a redirect,
+// uses a scheme other than http or https.
+var ErrUnsupportedScheme = errors.New("unsupported scheme")
+
 // Interface defines the interface for a URL resolver.
 type Interface interface {
 	Resolve(context.Context, string) (Result, error)
@@ -36,16 +46,71 @@ type Result struct {
 	ResolvedURL      string
 	Title            string
 	IntermediateURLs []string
+	Hops             []Hop
 	Coalesced        bool
+	Outcome          Outcome
+	RobotsDisallowed bool
+	SuspiciousHost   *SuspiciousHost
+	UpgradedToHTTPS  bool
+	HSTS             bool
+	Method           string
+
+	// StatusCode is the status of the final response, or 0 if no response
+	// was received. Note that it is 206 Partial Content if the server
+	// honored the Range header we send to limit the size of the response.
+	StatusCode int
+
+	// Interstitial names the auth or bot detection interstitial that was
+	// detected during resolution, if any.
+	Interstitial string
+
+	// File describes the resolved resource if it is not an HTML document,
+	// in which case no title is parsed.
+	File *FileInfo
+
+	// Feed describes the resolved resource if it is an RSS or Atom feed, in
+	// which case the feed's title is used.
+	Feed *FeedInfo
+
+	// details are only gathered for ResolveDetailed, which moves them into
+	// a DetailedResult.
+	details *resultDetails
 }
 
 // Resolver resolves URLs.
 type Resolver struct {
 	pool              *bufferpool.BufferPool
-	singleflightGroup *singleflight.Group
+	coalescer         *coalescer
 	timeout           time.Duration
 	transport         http.RoundTripper
-	tweetFetcher      tweetFetcher
+	client            *http.Client
+	tweetFetcher      TweetFetcher
+	redirectRewriters []RedirectRewriter
+	ipLiteralPolicy   IPLiteralPolicy
+	domainBlocklist   []string
+	domainAllowlist   []string
+	maxResponseSize   int64
+	hostLimiter       *hostLimiter
+	rateLimits        *rateLimiter
+	robots            *robotsChecker
+	cookieJar         http.CookieJar
+	maxRedirects      int
+	maxRedirectsSet   bool
+	httpsUpgrade      bool
+	unwrapDomains     []string
+	unwrappers        []Unwrapper
+	canonicalizer     *canonical.Canonicalizer
+	logger            *slog.Logger
+	hooks             Hooks
+	metrics           *Metrics
+	auditSink         AuditSink
+	headRequests      bool
+	maxBodySize       int
+	headOnly          bool
+	titleNormalizer   titleNormalizer
+
+	temporaryRedirectPolicy TemporaryRedirectPolicy
+	maxTitleSearchSize      int
 }
 
 var _ Interface = &Resolver{} // Resolver implements Interface
@@ -53,17 +118,59 @@ var _ Interface = &Resolver{} // Resolver implements Interface
 // New creates a new Resolver that uses the given transport to make HTTP
 // requests and applies the given timeout to the overall process (including any
 // redirects that must be followed).
-func New(transport http.RoundTripper, timeout time.Duration) *Resolver {
+func New(transport http.RoundTripper, timeout time.Duration, opts ...Option) *Resolver {
 	if timeout == 0 {
 		timeout = defaultTimeout
 	}
-	pool := bufferpool.New()
-	return &Resolver{
-		pool:              pool,
-		singleflightGroup: &singleflight.Group{},
+	tweetFetcher := newTweetFetcher(http.DefaultTransport, timeout, nil)
+	r := &Resolver{
+		coalescer:         &coalescer{},
 		timeout:           timeout,
 		transport:         transport,
-		tweetFetcher:      newTweetFetcher(http.DefaultTransport, timeout, pool),
+		tweetFetcher:      tweetFetcher,
+		redirectRewriters: []RedirectRewriter{stopAtInterstitials},
+		maxRedirects:      defaultMaxRedirects,
+		maxBodySize:       defaultMaxBodySize,
+
+		maxTitleSearchSize: defaultMaxTitleSearchSize,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	// Pooled buffers must be allowed to hold a full max body size read,
+	// plus room for the buffer's growth past it, or the buffers used by
+	// every ordinary resolution would be discarded instead of reused.
+	r.pool = bufferpool.New(bufferpool.WithMaxCapacity(2 * r.maxBodySize))
+	tweetFetcher.pool = r.pool
+	// The transport chain depends on the options above, so the client is
+	// built once they have been applied.
+	r.client = &http.Client{Transport: r.roundTripper(r.hostLimiter), Timeout: r.timeout}
+	return r
+}
+
+// BufferPoolStats reports on the usage of the pool of buffers shared by the
+// resolver's requests.
+func (r *Resolver) BufferPoolStats() bufferpool.Stats {
+	return r.pool.Stats()
+}
+
+// Option customizes a Resolver.
+type Option func(*Resolver)
+
+// RedirectRewriter is called with the target of each redirect before it is
+// followed, and returns the URL that should be followed instead. A rewriter
+// that does not need to change the target should return it unchanged.
+//
+// Returning http.ErrUseLastResponse stops following redirects, and the
+// previous hop is used as the resolved URL. Any other error aborts the
+// resolution.
+type RedirectRewriter func(target *url.URL) (*url.URL, error)
+
+// WithRedirectRewriters adds one or more RedirectRewriters, which are applied
+// in order to each redirect target after the built-in interstitial detection.
+func WithRedirectRewriters(rewriters ...RedirectRewriter) Option {
+	return func(r *Resolver) {
+		r.redirectRewriters = append(r.redirectRewriters, rewriters...)
 	}
 }
 
@@ -73,25 +180,46 @@ func New(transport http.RoundTripper, timeout time.Duration) *Resolver {
 func (r *Resolver) Resolve(ctx context.Context, givenURL string) (Result, error) {
 	// Immediately canonicalize the given URL to slightly increase the chance
 	// of coalescing multiple requests into one.
+	canonicalURL := givenURL
 	if u, err := url.Parse(givenURL); err == nil {
-		givenURL = Canonicalize(u)
+		canonicalURL = r.canonicalize(u)
 	}
 
-	val, err, coalesced := r.singleflightGroup.Do(givenURL, func() (interface{}, error) {
-		return r.doResolve(ctx, givenURL)
-	})
-
-	result := val.(Result)
+	result, coalesced, err := r.coalesce(ctx, canonicalURL)
 	result.Coalesced = coalesced
+	r.metrics.requestFinished(coalesced)
+	r.audit(ctx, givenURL, result, err)
 	return result, err
 }
 
 func (r *Resolver) doResolve(ctx context.Context, givenURL string) (Result, error) {
+	start := time.Now()
+	r.metrics.resolutionStarted()
+	result, err := r.resolve(ctx, givenURL)
+	result.Outcome = classifyOutcome(result, err)
+	result.SuspiciousHost = detectSuspiciousHost(result.ResolvedURL)
+	r.metrics.resolutionFinished(result, time.Since(start))
+	r.logResolution(ctx, givenURL, result, err)
+	if err != nil {
+		r.onError(ctx, givenURL, err)
+	}
+	return result, err
+}
+
+func (r *Resolver) resolve(ctx context.Context, givenURL string) (Result, error) {
 	result := Result{ResolvedURL: givenURL}
 
+	// The special cases below may resolve the given URL without fetching
+	// it, so it must be checked against the resolver's policies up front.
+	if u, err := url.Parse(givenURL); err == nil {
+		if err := r.checkURL(u); err != nil {
+			return result, err
+		}
+	}
+
 	// Short-circuit special case for tweet URLs, which we ask Twitter to help
 	// us resolve.
-	if tweetURL, ok := matchTweetURL(givenURL); ok {
+	if tweetURL, ok := r.matchTweetURL(givenURL); ok {
 		return r.resolveTweet(ctx, tweetURL, result)
 	}
 
@@ -101,23 +229,25 @@ func (r *Resolver) doResolve(ctx context.Context, givenURL string) (Result, erro
 	if encodedURL, ok := matchSailthruURL(givenURL); ok {
 		if decodedURL, err := decodeSailthruURL(encodedURL); err == nil {
 			// pretend like we resolved the Sailthru tracking URL
-			result.IntermediateURLs = append(result.IntermediateURLs, givenURL)
+			hop := Hop{URL: givenURL, Kind: HopUnwrap}
+			result.addHop(hop)
+			if target, err := url.Parse(decodedURL); err == nil {
+				if err := r.checkUnwrapped(ctx, hop, target); err != nil {
+					result.ResolvedURL = decodedURL
+					return result, err
+				}
+			}
 			givenURL = decodedURL
 		}
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", givenURL, nil)
+	givenURL, err := r.unwrap(ctx, givenURL, &result)
 	if err != nil {
+		result.ResolvedURL = givenURL
 		return result, err
 	}
 
-	if matchTcoURL(givenURL) {
-		req.Header.Set("User-Agent", "curl/7.64.1")
-	}
-
-	recorder := &redirectRecorder{&result}
-
-	resp, err := r.httpClient(recorder).Do(req)
+	resp, err := r.fetchWithUpgrade(ctx, givenURL, &result)
 	if err != nil {
 		// If there's a URL associated with the error, we still want to
 		// canonicalize it and return a partial result. This gives us a useful
@@ -128,28 +258,183 @@ func (r *Resolver) doResolve(ctx context.Context, givenURL string) (Result, erro
 		if urlErr, ok := err.(*url.Error); ok {
 			result.ResolvedURL = urlErr.URL
 			if intermediateURL, _ := url.Parse(urlErr.URL); intermediateURL != nil {
-				result.ResolvedURL = Canonicalize(intermediateURL)
+				// When a redirect is rejected by checkRedirect, the error's URL
+				// is the raw (and possibly relative) Location header, which we
+				// resolve against the request that returned it.
+				if resp != nil {
+					intermediateURL = resp.Request.URL.ResolveReference(intermediateURL)
+				}
+				result.ResolvedURL = r.canonicalize(intermediateURL)
 			}
 		}
 
+		// We deliberately stopped at the first URL outside of our unwrap
+		// domains, which is our result.
+		if errors.Is(err, errLeftUnwrapDomains) {
+			return result, nil
+		}
+
+		// Give a clearer error when a redirect leads us into a private
+		// network.
+		if len(result.IntermediateURLs) > 0 {
+			err = wrapPrivateNetworkErr(result.ResolvedURL, err)
+		}
+
 		return result, err
 	}
 	defer resp.Body.Close()
 
 	// At this point, we have at least resolved and canonicalized the URL,
 	// whether or not we can successfully extract a title.
-	result.ResolvedURL = Canonicalize(resp.Request.URL)
+	result.ResolvedURL = r.canonicalize(resp.Request.URL)
+	result.StatusCode = resp.StatusCode
+	result.HSTS = sentHSTS(resp)
+	if detailsWanted(ctx) {
+		result.details = &resultDetails{headers: detailedHeaders(resp.Header)}
+	}
+	r.applyTemporaryRedirectPolicy(&result, resp.Request.URL)
+
+	if isBotChallenge(resp) {
+		result.Interstitial = cloudflareChallengeFingerprint
+		return result, fmt.Errorf("%w: %s", ErrBotChallenge, resp.Request.URL.Host)
+	}
 
 	// Check again for the chance to special-case tweet URLs *after* following
 	// any redirects.
-	if tweetURL, ok := matchTweetURL(result.ResolvedURL); ok {
+	if tweetURL, ok := r.matchTweetURL(result.ResolvedURL); ok {
 		return r.resolveTweet(ctx, tweetURL, result)
 	}
 
-	result.Title, err = r.maybeParseTitle(resp)
+	if r.ipLiteralPolicy == IPLiteralNoTitle && isIPLiteral(resp.Request.URL) {
+		return result, nil
+	}
+
+	if r.robots != nil {
+		if !r.robots.allowed(ctx, r.robotsClient(), resp.Request.URL) {
+			result.RobotsDisallowed = true
+			return result, nil
+		}
+	}
+
+	// The caller may only want the resolved URL, in which case we don't
+	// read the body at all.
+	readBody := titleWanted(ctx)
+
+	if readBody && isFeedResponse(resp) {
+		if title, feed := r.maybeParseFeed(resp); feed != nil {
+			result.Title = r.titleNormalizer.normalize(title, resp.Request.URL.Hostname())
+			result.Feed = feed
+			r.onTitleParsed(ctx, result.ResolvedURL, result.Title)
+			return result, nil
+		}
+	}
+
+	if isFileResponse(resp) {
+		result.File = fileInfo(resp)
+		if readBody && isImage(result.File.MIMEType) {
+			result.File.Image = r.imageInfo(resp)
+		}
+		return result, nil
+	}
+
+	if !readBody {
+		return result, nil
+	}
+
+	result.Title, err = r.maybeParseTitle(resp, result.details)
+	if err == nil {
+		result.Title = r.titleNormalizer.normalize(result.Title, resp.Request.URL.Hostname())
+		r.onTitleParsed(ctx, result.ResolvedURL, result.Title)
+	}
 	return result, err
 }
 
+// fetch makes a request for the given URL using the given method, following
+// redirects and recording them in the given result.
+func (r *Resolver) fetch(ctx context.Context, method string, givenURL string, result *Result) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, givenURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.checkURL(req.URL); err != nil {
+		return nil, err
+	}
+	if err := r.checkUnwrapDomains(req.URL); err != nil {
+		return nil, &url.Error{Op: "Get", URL: givenURL, Err: err}
+	}
+
+	if matchTcoURL(givenURL) {
+		req.Header.Set("User-Agent", "curl/7.64.1")
+	}
+
+	// We only need the head of the final response to find its title, so we
+	// ask for no more than we'll read while searching for it. We stop
+	// reading as soon as the title is found, so most responses are cut off
+	// well before this. Servers that ignore the Range header send the whole
+	// body as usual, and redirects are unaffected.
+	//
+	// Note: net/http does not transparently request and decompress gzipped
+	// responses when a Range header is set, so we do that ourselves.
+	req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", r.titleSearchLimit()-1))
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	maxRedirects, maxRedirectsSet := r.maxRedirectsFor(ctx)
+	recorder := &redirectRecorder{
+		result:          result,
+		rewriters:       r.redirectRewriters,
+		checkURL:        r.checkRedirectURL,
+		canonicalize:    r.canonicalize,
+		maxRedirects:    maxRedirects,
+		maxRedirectsSet: maxRedirectsSet,
+		hopStart:        time.Now(),
+		onRedirect:      r.hooks.OnRedirect,
+	}
+
+	return r.httpClient(recorder).Do(req)
+}
+
+// checkRedirectURL returns an error if the given redirect target is
+// disallowed by the resolver's policies, or if it should not be followed
+// because it is outside of the resolver's unwrap domains.
+func (r *Resolver) checkRedirectURL(u *url.URL) error {
+	if err := r.checkURL(u); err != nil {
+		return err
+	}
+	return r.checkUnwrapDomains(u)
+}
+
+// checkURL returns an error if the given URL, which may be the given URL or
+// the target of a redirect, is disallowed by the resolver's policies.
+func (r *Resolver) checkURL(u *url.URL) error {
+	if err := checkScheme(u); err != nil {
+		return err
+	}
+	if err := checkIPLiteral(r.ipLiteralPolicy, u); err != nil {
+		return err
+	}
+	return checkDomain(r.domainBlocklist, r.domainAllowlist, u)
+}
+
+// checkScheme returns an error if the given URL cannot be fetched over HTTP.
+func checkScheme(u *url.URL) error {
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedScheme, u.Scheme)
+	}
+}
+
+// matchTweetURL matches URLs pointing to tweets, unless the resolver's
+// handling of tweets is disabled.
+func (r *Resolver) matchTweetURL(s string) (string, bool) {
+	if r.tweetFetcher == nil {
+		return "", false
+	}
+	return matchTweetURL(s)
+}
+
 func (r *Resolver) resolveTweet(ctx context.Context, tweetURL string, result Result) (Result, error) {
 	tweet, err := r.tweetFetcher.Fetch(ctx, tweetURL)
 	if err != nil {
@@ -160,57 +445,217 @@ func (r *Resolver) resolveTweet(ctx context.Context, tweetURL string, result Res
 	}
 
 	result.ResolvedURL = tweet.URL
-	result.Title = tweet.Text
+	result.Title = r.titleNormalizer.normalize(tweet.Text, "")
 	return result, nil
 }
 
+// httpClient returns a client for a single fetch, which shares the
+// resolver's transport but records redirects with the given recorder and,
+// unless the resolver has a shared cookie jar, keeps its own cookies.
 func (r *Resolver) httpClient(recorder *redirectRecorder) *http.Client {
-	cookieJar, _ := cookiejar.New(&cookiejar.Options{
-		PublicSuffixList: publicsuffix.List,
-	})
-	return &http.Client{
-		CheckRedirect: recorder.checkRedirect,
-		Jar:           cookieJar,
-		Transport:     r.transport,
-		Timeout:       r.timeout,
+	client := *r.client
+	client.CheckRedirect = recorder.checkRedirect
+	client.Jar = r.cookieJar
+	if client.Jar == nil {
+		client.Jar = &lazyCookieJar{}
+	}
+	return &client
+}
+
+// roundTripper wraps the resolver's transport with any additional checks
+// that must be applied to every request and response, limiting per-host
+// concurrency with the given limiter, if any.
+func (r *Resolver) roundTripper(hostLimiter *hostLimiter) http.RoundTripper {
+	transport := r.transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	if hostLimiter != nil {
+		transport = &hostLimitTransport{transport, hostLimiter}
+	}
+	if r.rateLimits != nil {
+		transport = &rateLimitTransport{transport, r.rateLimits}
+	}
+	if r.maxResponseSize > 0 {
+		transport = &maxSizeTransport{transport, r.maxResponseSize}
 	}
+	return &locationCheckingTransport{transport}
 }
 
-func (r *Resolver) maybeParseTitle(resp *http.Response) (string, error) {
+// maybeParseTitle returns the title of the HTML document in the given
+// response, also parsing its metadata into details if given.
+func (r *Resolver) maybeParseTitle(resp *http.Response, details *resultDetails) (string, error) {
 	if !shouldParseTitle(resp) {
 		return "", nil
 	}
 
-	body, err := r.peekBody(resp)
+	// The peeked body may share memory with the pooled buffer, so we must
+	// be done with it before the buffer is returned to the pool.
+	buf := r.pool.Get()
+	defer r.pool.Put(buf)
+
+	body, err := r.peekBody(resp, buf, r.stopReadingPattern(details != nil))
+	r.metrics.bodyRead(buf.Len())
 	if err != nil {
+		r.metrics.titleExtracted("", err)
 		return "", err
 	}
 
-	return findTitle(body), nil
+	title := findTitle(body)
+	r.metrics.titleExtracted(title, nil)
+	if details != nil {
+		details.metadata = parsePageMetadata(body, resp.Request.URL)
+	}
+	return title, nil
 }
 
-func (r *Resolver) peekBody(resp *http.Response) ([]byte, error) {
-	buf := r.pool.Get()
-	defer r.pool.Put(buf)
-
-	if _, err := io.Copy(buf, io.LimitReader(resp.Body, maxBodySize)); err != nil {
+func (r *Resolver) peekBody(resp *http.Response, buf *bytes.Buffer, stop *regexp.Regexp) ([]byte, error) {
+	body, err := decompressBody(resp)
+	if err != nil {
 		return nil, fmt.Errorf("error reading response: %w", err)
 	}
+	defer body.Close()
+
+	if err := r.readTitleSearch(buf, body, stop); err != nil {
+		// A partial response to our Range request may cut off a compressed
+		// body mid-stream, which is fine since we only want its head.
+		if !(resp.StatusCode == http.StatusPartialContent && errors.Is(err, io.ErrUnexpectedEOF)) {
+			return nil, fmt.Errorf("error reading response: %w", err)
+		}
+	}
+
+	head := buf.Bytes()
+	if r.headOnly {
+		head = truncateAtBody(head)
+	}
 
-	body, err := decodeBody(buf.Bytes(), resp.Header.Get("Content-Type"))
+	decoded, err := decodeBody(head, resp.Header.Get("Content-Type"))
 	if err != nil {
 		return nil, fmt.Errorf("error decoding response: %w", err)
 	}
 
-	return body, nil
+	return decoded, nil
+}
+
+// decompressBody returns a reader for the given response's body that
+// decompresses it if necessary.
+func decompressBody(resp *http.Response) (io.ReadCloser, error) {
+	if resp.Uncompressed || !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		return resp.Body, nil
+	}
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		// an empty gzipped body is just an empty body
+		if err == io.EOF {
+			return io.NopCloser(bytes.NewReader(nil)), nil
+		}
+		return nil, err
+	}
+	return gz, nil
 }
 
 func shouldParseTitle(resp *http.Response) bool {
+	// Our Range request is only unsatisfiable if the body is empty.
+	if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		return false
+	}
 	contentType := resp.Header.Get("Content-Type")
 	return strings.Contains(contentType, "html") || contentType == ""
 }
 
+// headChunkSize is the most we'll read from a response body at once while
+// looking for its title.
+const headChunkSize = 4 * 1024
+
+// titleFoundRegex matches once enough of a document has been read to find
+// its title: either a complete <title> element or the end of the <head>.
+var titleFoundRegex = regexp.MustCompile(`(?i)<title[^>]*?>[^<]*<|</head`)
+
+// readTitleSearch reads the head of body into buf until stop matches, first
+// up to the max body size and then, if the body continues, in increments of
+// that size up to the title search limit (which is also the extent of our
+// Range request).
+func (r *Resolver) readTitleSearch(buf *bytes.Buffer, body io.Reader, stop *regexp.Regexp) error {
+	searchLimit := r.titleSearchLimit()
+	for limit := r.maxBodySize; ; limit = min(limit+r.maxBodySize, searchLimit) {
+		done, err := readHead(buf, body, limit, stop)
+		if err != nil || done || limit >= searchLimit {
+			return err
+		}
+	}
+}
+
+// stopOverlap is how far back into what has already been read a new match
+// of a stop pattern may start. It comfortably covers tags like </head> and
+// <title> with attributes; a longer title that spans two reads just means
+// reading further before stopping, and it will still be found.
+const stopOverlap = 512
+
+// readHead reads body into buf until stop matches what has been read,
+// reaching EOF, or reading limit bytes, so that we don't wait on or pay for
+// the rest of a large document. It reports whether reading stopped before
+// reaching the limit.
+func readHead(buf *bytes.Buffer, body io.Reader, limit int, stop *regexp.Regexp) (bool, error) {
+	for buf.Len() < limit {
+		// Any new match must start at or after the last tag opened in
+		// what we've already read. Only the last stopOverlap bytes are
+		// searched for it, so that each read is scanned in bounded time
+		// no matter how much has been read without finding a tag.
+		from := buf.Len()
+		window := max(buf.Len()-stopOverlap, 0)
+		if idx := bytes.LastIndexByte(buf.Bytes()[window:], '<'); idx >= 0 {
+			from = window + idx
+		}
+
+		buf.Grow(headChunkSize)
+		chunk := buf.AvailableBuffer()[:min(headChunkSize, limit-buf.Len())]
+		n, err := body.Read(chunk)
+		buf.Write(chunk[:n])
+		if err == io.EOF {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if stop.Match(buf.Bytes()[from:]) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// utf8BOM is the byte order mark that may begin a UTF-8 document.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// byteOrderMarks are the byte order marks that may begin a document, along
+// with the encodings they indicate. A UTF-8 document needs no decoding.
+var byteOrderMarks = []struct {
+	bom []byte
+	enc encoding.Encoding
+}{
+	{utf8BOM, nil},
+	{[]byte{0xFE, 0xFF}, unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)},
+	{[]byte{0xFF, 0xFE}, unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)},
+}
+
 func decodeBody(body []byte, contentType string) ([]byte, error) {
+	// A byte order mark trumps any declared encoding, and is stripped so
+	// that it can't end up in a title.
+	for _, m := range byteOrderMarks {
+		if bytes.HasPrefix(body, m.bom) {
+			if m.enc == nil {
+				return body[len(m.bom):], nil
+			}
+			return m.enc.NewDecoder().Bytes(body[len(m.bom):])
+		}
+	}
+
+	// Most documents are UTF-8, which needs no decoding, so we skip
+	// sniffing the encoding when we can.
+	if declaresUTF8(contentType) {
+		return body, nil
+	}
+
 	enc, encName, _ := charset.DetermineEncoding(body, contentType)
 	if encName == "utf-8" {
 		return body, nil
@@ -218,6 +663,22 @@ func decodeBody(body []byte, contentType string) ([]byte, error) {
 	return enc.NewDecoder().Bytes(body)
 }
 
+// declaresUTF8 returns true if the given Content-Type header declares the
+// utf-8 charset.
+func declaresUTF8(contentType string) bool {
+	for contentType != "" {
+		var param string
+		param, contentType, _ = strings.Cut(contentType, ";")
+		name, value, ok := strings.Cut(param, "=")
+		if !ok || !strings.EqualFold(strings.TrimSpace(name), "charset") {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+		return strings.EqualFold(value, "utf-8") || strings.EqualFold(value, "utf8")
+	}
+	return false
+}
+
 // Using this naive regex has the nice side effect of preventing
 // us from ingesting malformed & potentially malicious titles,
 // so this bad title
@@ -236,29 +697,128 @@ func findTitle(body []byte) string {
 	if len(matches) < 2 {
 		return ""
 	}
-	return html.UnescapeString(string(bytes.TrimSpace(matches[1])))
+	// A stray byte order mark (aka zero width no-break space) is invisible,
+	// but would defeat exact matches against the title.
+	title := html.UnescapeString(string(bytes.TrimSpace(matches[1])))
+	return strings.TrimSpace(strings.ReplaceAll(title, "\ufeff", ""))
 }
 
 type redirectRecorder struct {
-	result *Result
+	result       *Result
+	rewriters    []RedirectRewriter
+	checkURL     func(*url.URL) error
+	canonicalize func(*url.URL) string
+	maxRedirects int
+	// maxRedirectsSet is true if maxRedirects was set explicitly, in which
+	// case exceeding it is an error rather than a silent stop
+	maxRedirectsSet bool
+	hopStart        time.Time
+	onRedirect      func(ctx context.Context, hop Hop, target *url.URL) error
+}
+
+// interstitialFingerprints identify well-known auth or bot detection
+// interstitials by their URLs.
+var interstitialFingerprints = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"instagram_login", regexp.MustCompile(`\binstagram\.com/accounts/login/`)},
+	{"forbes_welcome", regexp.MustCompile(`\bforbes\.com/forbes/welcome`)},
+	{"bloomberg_tos", regexp.MustCompile(`\bbloomberg\.com/tosv2.html`)},
+}
+
+// interstitialError wraps http.ErrUseLastResponse to signal that redirects
+// were stopped because of an interstitial, rather than by some other
+// rewriter.
+type interstitialError struct {
+	fingerprint string
+}
+
+func (e *interstitialError) Error() string {
+	return fmt.Sprintf("redirected to interstitial %s: %s", e.fingerprint, http.ErrUseLastResponse)
+}
+
+func (e *interstitialError) Unwrap() error {
+	return http.ErrUseLastResponse
 }
 
-var useLastResponseInterstiatilPattern = listToRegexp("(", ")", []string{
-	`\binstagram\.com/accounts/login/`,
-	`\bforbes\.com/forbes/welcome`,
-	`\bbloomberg\.com/tosv2.html`,
-})
+// stopAtInterstitials is a RedirectRewriter that stops following redirects
+// when we are redirected to a well-known auth or bot detection interstitial,
+// so that the previous hop is used as our final URL.
+func stopAtInterstitials(target *url.URL) (*url.URL, error) {
+	for _, fp := range interstitialFingerprints {
+		if fp.pattern.MatchString(target.String()) {
+			return nil, &interstitialError{fp.name}
+		}
+	}
+	return target, nil
+}
 
 func (r *redirectRecorder) checkRedirect(req *http.Request, via []*http.Request) error {
-	// Looks like we were redirected to a well-known auth or bot detection
-	// interstitial, so we use the previous hop as our final URL.
-	if useLastResponseInterstiatilPattern.MatchString(req.URL.String()) {
+	err := r.rewrite(req)
+	if errors.Is(err, http.ErrUseLastResponse) {
+		var interstitial *interstitialError
+		if errors.As(err, &interstitial) {
+			r.result.Outcome = OutcomeChallengeFallback
+			r.result.Interstitial = interstitial.fingerprint
+		}
+		// The previous hop becomes our final URL, so it is not recorded as
+		// an intermediate URL.
 		return http.ErrUseLastResponse
 	}
 
-	r.result.IntermediateURLs = append(r.result.IntermediateURLs, via[len(via)-1].URL.String())
-	if len(via) >= maxRedirects {
+	now := time.Now()
+	hop := Hop{
+		URL:        via[len(via)-1].URL.String(),
+		Kind:       HopRedirect,
+		StatusCode: req.Response.StatusCode,
+		Latency:    now.Sub(r.hopStart),
+		Permanent:  isPermanentRedirect(req.Response.StatusCode),
+	}
+	r.result.addHop(hop)
+	r.hopStart = now
+	if err != nil {
+		return err
+	}
+	if !r.maxRedirectsSet && len(via) >= r.maxRedirects {
 		return http.ErrUseLastResponse
 	}
+	if err := r.checkURL(req.URL); err != nil {
+		return err
+	}
+	if err := checkRedirectLoop(req, via, r.canonicalize); err != nil {
+		return err
+	}
+	// via includes the original request, so it holds one more request than
+	// the number of redirects followed so far
+	if r.maxRedirectsSet && len(via) > r.maxRedirects {
+		urls := make([]string, 0, len(via)+1)
+		for _, hop := range via {
+			urls = append(urls, hop.URL.String())
+		}
+		return &TooManyRedirectsError{
+			Max:  r.maxRedirects,
+			URLs: append(urls, req.URL.String()),
+		}
+	}
+	if r.onRedirect != nil {
+		return r.onRedirect(req.Context(), hop, req.URL)
+	}
+	return nil
+}
+
+// rewrite applies each RedirectRewriter in turn to the upcoming request's
+// URL.
+func (r *redirectRecorder) rewrite(req *http.Request) error {
+	for _, rewrite := range r.rewriters {
+		target, err := rewrite(req.URL)
+		if err != nil {
+			return err
+		}
+		if target.String() != req.URL.String() {
+			req.URL = target
+			req.Host = ""
+		}
+	}
 	return nil
 }
@@ -9,6 +9,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math/rand"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -23,6 +24,9 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+
+	"github.com/mccutchen/urlresolver/canonical"
 )
 
 type titleTestCase struct {
@@ -66,6 +70,8 @@ func TestResolver(t *testing.T) {
 			wantResult: Result{
 				ResolvedURL: "/foo",
 				Title:       "page title",
+				Outcome:     OutcomeOK,
+				StatusCode:  200,
 			},
 		},
 		{
@@ -82,6 +88,8 @@ func TestResolver(t *testing.T) {
 				ResolvedURL:      "/b",
 				Title:            "page title",
 				IntermediateURLs: []string{"/a"},
+				Outcome:          OutcomeOK,
+				StatusCode:       200,
 			},
 		},
 		{
@@ -93,9 +101,11 @@ func TestResolver(t *testing.T) {
 			},
 			givenURL: "/0",
 			wantResult: Result{
-				ResolvedURL:      fmt.Sprintf("/%d", maxRedirects-1),
+				ResolvedURL:      fmt.Sprintf("/%d", defaultMaxRedirects-1),
 				Title:            "",
 				IntermediateURLs: []string{"/0", "/1", "/2", "/3", "/4"},
+				Outcome:          OutcomePartialNoTitle,
+				StatusCode:       302,
 			},
 		},
 		{
@@ -131,6 +141,8 @@ func TestResolver(t *testing.T) {
 				ResolvedURL:      "/b",
 				Title:            "🍪",
 				IntermediateURLs: []string{"/a"},
+				Outcome:          OutcomeOK,
+				StatusCode:       200,
 			},
 		},
 		{
@@ -151,6 +163,9 @@ func TestResolver(t *testing.T) {
 				ResolvedURL:      "/forbes",
 				Title:            "",
 				IntermediateURLs: []string{"/start"},
+				Outcome:          OutcomeChallengeFallback,
+				StatusCode:       302,
+				Interstitial:     "forbes_welcome",
 			},
 		},
 		{
@@ -170,6 +185,9 @@ func TestResolver(t *testing.T) {
 				ResolvedURL:      "/instagram",
 				Title:            "",
 				IntermediateURLs: []string{"/start"},
+				Outcome:          OutcomeChallengeFallback,
+				StatusCode:       302,
+				Interstitial:     "instagram_login",
 			},
 		},
 		{
@@ -189,6 +207,9 @@ func TestResolver(t *testing.T) {
 				ResolvedURL:      "/bloomberg",
 				Title:            "",
 				IntermediateURLs: []string{"/start"},
+				Outcome:          OutcomeChallengeFallback,
+				StatusCode:       302,
+				Interstitial:     "bloomberg_tos",
 			},
 		},
 		{
@@ -205,6 +226,7 @@ func TestResolver(t *testing.T) {
 			timeout:  10 * time.Millisecond,
 			wantResult: Result{
 				ResolvedURL: "/foo",
+				Outcome:     OutcomeTimeoutPartial,
 			},
 			wantErr: context.DeadlineExceeded,
 		},
@@ -232,6 +254,7 @@ func TestResolver(t *testing.T) {
 					// is canonicalized
 					"/long-url?AAA=AAA&mmm=mmm&zzz=zzz",
 				},
+				Outcome: OutcomeTimeoutPartial,
 			},
 			wantErr: context.DeadlineExceeded,
 		},
@@ -259,6 +282,8 @@ func TestResolver(t *testing.T) {
 				ResolvedURL:      "/bar", // note, we still got a usefully resolved URL, despite the expected error
 				Title:            "",
 				IntermediateURLs: []string{"/foo"},
+				Outcome:          OutcomeTimeoutPartial,
+				StatusCode:       200,
 			},
 			wantErr: context.DeadlineExceeded,
 		},
@@ -272,6 +297,8 @@ func TestResolver(t *testing.T) {
 			wantResult: Result{
 				ResolvedURL: "/foo",
 				Title:       "page title",
+				Outcome:     OutcomeOK,
+				StatusCode:  200,
 			},
 		},
 		{
@@ -284,6 +311,8 @@ func TestResolver(t *testing.T) {
 			wantResult: Result{
 				ResolvedURL: "/foo",
 				Title:       "page title",
+				Outcome:     OutcomeOK,
+				StatusCode:  200,
 			},
 		},
 		{
@@ -296,6 +325,8 @@ func TestResolver(t *testing.T) {
 			wantResult: Result{
 				ResolvedURL: "/foo",
 				Title:       "page title",
+				Outcome:     OutcomeOK,
+				StatusCode:  200,
 			},
 		},
 		{
@@ -308,6 +339,24 @@ func TestResolver(t *testing.T) {
 			wantResult: Result{
 				ResolvedURL: "/foo",
 				Title:       "",
+				Outcome:     OutcomeFile,
+				StatusCode:  200,
+				File:        &FileInfo{Name: "foo", MIMEType: "application/json", Size: 51},
+			},
+		},
+		{
+			name: "not found",
+			handlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html")
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`<html><head><title>Page not found</title></head></html>`))
+			},
+			givenURL: "/foo",
+			wantResult: Result{
+				ResolvedURL: "/foo",
+				Title:       "Page not found",
+				Outcome:     OutcomeNotFound,
+				StatusCode:  404,
 			},
 		},
 		{
@@ -321,6 +370,8 @@ func TestResolver(t *testing.T) {
 			wantResult: Result{
 				ResolvedURL: "/foo",
 				Title:       "Iñtërnâtiônàlizætiøn",
+				Outcome:     OutcomeOK,
+				StatusCode:  200,
 			},
 		},
 		{
@@ -334,6 +385,8 @@ func TestResolver(t *testing.T) {
 			wantResult: Result{
 				ResolvedURL: "/foo",
 				Title:       "Iñtërnâtiônàlizætiøn",
+				Outcome:     OutcomeOK,
+				StatusCode:  200,
 			},
 		},
 		{
@@ -347,6 +400,8 @@ func TestResolver(t *testing.T) {
 			wantResult: Result{
 				ResolvedURL: "/foo",
 				Title:       "Iñtërnâtiônàlizætiøn",
+				Outcome:     OutcomeOK,
+				StatusCode:  200,
 			},
 		},
 		{
@@ -360,6 +415,8 @@ func TestResolver(t *testing.T) {
 			wantResult: Result{
 				ResolvedURL: "/foo",
 				Title:       "Iñtërnâtiônàlizætiøn",
+				Outcome:     OutcomeOK,
+				StatusCode:  200,
 			},
 		},
 		{
@@ -375,6 +432,8 @@ func TestResolver(t *testing.T) {
 			wantResult: Result{
 				ResolvedURL: "/foo",
 				Title:       "Iñtërnâtiônàlizætiøn",
+				Outcome:     OutcomeOK,
+				StatusCode:  200,
 			},
 		},
 		{
@@ -391,6 +450,8 @@ func TestResolver(t *testing.T) {
 			wantResult: Result{
 				ResolvedURL: "/foo",
 				Title:       "Iñtërnâtiônàlizætiøn",
+				Outcome:     OutcomeOK,
+				StatusCode:  200,
 			},
 		},
 		{
@@ -400,13 +461,33 @@ func TestResolver(t *testing.T) {
 				w.Header().Set("Content-Encoding", "gzip")
 				w2 := gzip.NewWriter(w)
 				defer w2.Close()
-				body := fmt.Sprintf("<html><head><title>Iñtërnâtiônàlizætiøn</title></head><body>%s</body></html>", strings.Repeat("*", maxBodySize*2))
+				body := fmt.Sprintf("<html><head><title>Iñtërnâtiônàlizætiøn</title></head><body>%s</body></html>", strings.Repeat("*", defaultMaxTitleSearchSize*2))
 				mustWriteAll(t, w2, body)
 			},
 			givenURL: "/foo",
 			wantResult: Result{
 				ResolvedURL: "/foo",
 				Title:       "Iñtërnâtiônàlizætiøn",
+				Outcome:     OutcomeOK,
+				StatusCode:  200,
+			},
+		},
+		{
+			name: "stops reading once title is found",
+			handlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html")
+				mustWriteAll(t, w, "<html><head><title>page title</title>")
+				w.(http.Flusher).Flush()
+				// never finish the response, so the resolver only succeeds
+				// if it stops reading early
+				<-r.Context().Done()
+			},
+			givenURL: "/foo",
+			wantResult: Result{
+				ResolvedURL: "/foo",
+				Title:       "page title",
+				Outcome:     OutcomeOK,
+				StatusCode:  200,
 			},
 		},
 		{
@@ -422,6 +503,65 @@ func TestResolver(t *testing.T) {
 			wantResult: Result{
 				ResolvedURL: "/foo",
 				Title:       "",
+				Outcome:     OutcomeError,
+				StatusCode:  200,
+			},
+		},
+		{
+			name: "range request honored",
+			handlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Range") != fmt.Sprintf("bytes=0-%d", defaultMaxTitleSearchSize-1) {
+					t.Errorf("unexpected Range header: %q", r.Header.Get("Range"))
+				}
+				body := fmt.Sprintf("<html><head><title>page title</title></head><body>%s</body></html>", strings.Repeat("*", defaultMaxTitleSearchSize*2))
+				w.Header().Set("Content-Type", "text/html")
+				http.ServeContent(w, r, "", time.Time{}, strings.NewReader(body))
+			},
+			givenURL: "/foo",
+			wantResult: Result{
+				ResolvedURL: "/foo",
+				Title:       "page title",
+				Outcome:     OutcomeOK,
+				StatusCode:  206,
+			},
+		},
+		{
+			name: "range request honored for gzipped body",
+			handlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				// gzip a body that will not compress, so the range cuts off
+				// the gzip stream
+				buf := &bytes.Buffer{}
+				w2 := gzip.NewWriter(buf)
+				mustWriteAll(t, w2, "<html><head><title>page title</title></head><body>")
+				io.CopyN(w2, rand.New(rand.NewSource(1)), defaultMaxTitleSearchSize+defaultMaxBodySize)
+				w2.Close()
+				if buf.Len() <= defaultMaxTitleSearchSize {
+					t.Errorf("expected gzipped body larger than %d bytes, got %d", defaultMaxTitleSearchSize, buf.Len())
+				}
+				w.Header().Set("Content-Type", "text/html")
+				w.Header().Set("Content-Encoding", "gzip")
+				http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(buf.Bytes()))
+			},
+			givenURL: "/foo",
+			wantResult: Result{
+				ResolvedURL: "/foo",
+				Title:       "page title",
+				Outcome:     OutcomeOK,
+				StatusCode:  206,
+			},
+		},
+		{
+			name: "range not satisfiable",
+			handlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html")
+				w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+				mustWriteAll(t, w, "<title>416 Range Not Satisfiable</title>")
+			},
+			givenURL: "/foo",
+			wantResult: Result{
+				ResolvedURL: "/foo",
+				Outcome:     OutcomePartialNoTitle,
+				StatusCode:  416,
 			},
 		},
 		{
@@ -434,6 +574,8 @@ func TestResolver(t *testing.T) {
 			wantResult: Result{
 				ResolvedURL: "/foo",
 				Title:       "OK",
+				Outcome:     OutcomeOK,
+				StatusCode:  200,
 			},
 		},
 	}
@@ -469,7 +611,7 @@ func TestResolver(t *testing.T) {
 				tc.wantResult.IntermediateURLs[idx] = renderURL(srv.URL, hop)
 			}
 
-			assert.Equal(t, tc.wantResult, result)
+			assert.Equal(t, tc.wantResult, withoutHops(t, result))
 		})
 	}
 
@@ -489,6 +631,8 @@ func TestResolver(t *testing.T) {
 			Title:       "title",
 			ResolvedURL: srv.URL,
 			Coalesced:   true,
+			Outcome:     OutcomeOK,
+			StatusCode:  200,
 		}
 
 		resolver := New(newSafeTestTransport(t), 0)
@@ -499,12 +643,12 @@ func TestResolver(t *testing.T) {
 			go func(i int) {
 				defer wg.Done()
 				// note: URL query param varies, but it's a param that will be
-				// stripped by initial canonicalization before the singleflight
+				// stripped by initial canonicalization before the coalescing
 				// check happens, so all requests should be coalesced.
 				url := fmt.Sprintf("%s?utm_campaign=%d", srv.URL, i)
 				result, err := resolver.Resolve(context.Background(), url)
 				assert.NoError(t, err)
-				assert.Equal(t, wantResult, result)
+				assert.Equal(t, wantResult, withoutHops(t, result))
 			}(i)
 		}
 		wg.Wait()
@@ -512,6 +656,54 @@ func TestResolver(t *testing.T) {
 		assert.Equal(t, int64(1), counter, "expected all requests coalesced into 1")
 	})
 
+	t.Run("an impatient caller does not cancel a coalesced resolution", func(t *testing.T) {
+		t.Parallel()
+
+		var counter int64
+		received := make(chan struct{}, 1)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt64(&counter, 1)
+			received <- struct{}{}
+			<-time.After(250 * time.Millisecond)
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><title>title</title></head></html>`))
+		}))
+		defer srv.Close()
+
+		resolver := New(newSafeTestTransport(t), 0)
+
+		impatientCtx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := resolver.Resolve(impatientCtx, srv.URL)
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+			assert.Equal(t, Result{
+				ResolvedURL: srv.URL,
+				Coalesced:   true,
+				Outcome:     OutcomeTimeoutPartial,
+			}, result)
+		}()
+
+		// wait for the first caller's resolution to start before joining it
+		<-received
+		result, err := resolver.Resolve(context.Background(), srv.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, Result{
+			Title:       "title",
+			ResolvedURL: srv.URL,
+			Coalesced:   true,
+			Outcome:     OutcomeOK,
+			StatusCode:  200,
+		}, withoutHops(t, result))
+
+		wg.Wait()
+		assert.Equal(t, int64(1), counter, "expected both requests coalesced into 1")
+	})
+
 	// an invalid URL is the only way to get an error out of Resolve
 	t.Run("invalid URL error", func(t *testing.T) {
 		t.Parallel()
@@ -519,7 +711,7 @@ func TestResolver(t *testing.T) {
 		resolver := New(newSafeTestTransport(t), 0)
 		result, err := resolver.Resolve(context.Background(), "%%")
 		assertErrorsMatch(t, errors.New("invalid URL escape"), err)
-		assert.Equal(t, Result{ResolvedURL: "%%"}, result)
+		assert.Equal(t, Result{ResolvedURL: "%%", Outcome: OutcomeError}, result)
 	})
 }
 
@@ -552,7 +744,131 @@ func TestRedirectHops(t *testing.T) {
 			renderURL(srv.URL, "/a"),
 			renderURL(srv.URL, "/b"),
 		},
-	}, result)
+		Outcome:    OutcomeOK,
+		StatusCode: 200,
+	}, withoutHops(t, result))
+}
+
+func TestRedirectRewriters(t *testing.T) {
+	t.Parallel()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			http.Redirect(w, r, "/a", http.StatusFound)
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusFound)
+		case "/b":
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`<title>B</title>`))
+		case "/c":
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`<title>C</title>`))
+		}
+	})
+
+	testCases := map[string]struct {
+		rewriters  []RedirectRewriter
+		wantResult Result
+		wantErr    error
+	}{
+		"no rewriters": {
+			wantResult: Result{
+				ResolvedURL:      "/b",
+				Title:            "B",
+				IntermediateURLs: []string{"", "/a"},
+				Outcome:          OutcomeOK,
+				StatusCode:       200,
+			},
+		},
+		"target rewritten": {
+			rewriters: []RedirectRewriter{
+				func(target *url.URL) (*url.URL, error) {
+					if target.Path == "/b" {
+						return target.ResolveReference(&url.URL{Path: "/c"}), nil
+					}
+					return target, nil
+				},
+			},
+			wantResult: Result{
+				ResolvedURL:      "/c",
+				Title:            "C",
+				IntermediateURLs: []string{"", "/a"},
+				Outcome:          OutcomeOK,
+				StatusCode:       200,
+			},
+		},
+		"rewriters applied in order": {
+			rewriters: []RedirectRewriter{
+				func(target *url.URL) (*url.URL, error) {
+					return target.ResolveReference(&url.URL{Path: "/c"}), nil
+				},
+				func(target *url.URL) (*url.URL, error) {
+					if target.Path == "/c" {
+						return target.ResolveReference(&url.URL{Path: "/b"}), nil
+					}
+					return target, nil
+				},
+			},
+			wantResult: Result{
+				ResolvedURL:      "/b",
+				Title:            "B",
+				IntermediateURLs: []string{""},
+				Outcome:          OutcomeOK,
+				StatusCode:       200,
+			},
+		},
+		"redirects stopped": {
+			rewriters: []RedirectRewriter{
+				func(target *url.URL) (*url.URL, error) {
+					if target.Path == "/b" {
+						return nil, http.ErrUseLastResponse
+					}
+					return target, nil
+				},
+			},
+			wantResult: Result{
+				ResolvedURL:      "/a",
+				IntermediateURLs: []string{""},
+				Outcome:          OutcomePartialNoTitle,
+				StatusCode:       302,
+			},
+		},
+		"rewriter error aborts resolution": {
+			rewriters: []RedirectRewriter{
+				func(target *url.URL) (*url.URL, error) {
+					return nil, errors.New("rewrite error")
+				},
+			},
+			wantResult: Result{
+				ResolvedURL:      "/a",
+				IntermediateURLs: []string{""},
+				Outcome:          OutcomeError,
+			},
+			wantErr: errors.New("rewrite error"),
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewServer(handler)
+			defer srv.Close()
+
+			resolver := New(newSafeTestTransport(t), 0, WithRedirectRewriters(tc.rewriters...))
+			result, err := resolver.Resolve(context.Background(), srv.URL)
+			assertErrorsMatch(t, tc.wantErr, err)
+
+			tc.wantResult.ResolvedURL = renderURL(srv.URL, tc.wantResult.ResolvedURL)
+			for idx, hop := range tc.wantResult.IntermediateURLs {
+				tc.wantResult.IntermediateURLs[idx] = renderURL(srv.URL, hop)
+			}
+			assert.Equal(t, tc.wantResult, withoutHops(t, result))
+		})
+	}
 }
 
 func TestSailthruHandling(t *testing.T) {
@@ -576,12 +892,14 @@ func TestSailthruHandling(t *testing.T) {
 	wantResult := Result{
 		ResolvedURL:      srv.URL + "/wrapped-target",
 		IntermediateURLs: []string{givenURL},
+		Outcome:          OutcomePartialNoTitle,
+		StatusCode:       200,
 	}
 
 	resolver := New(newSafeTestTransport(t), 0)
 	gotResult, err := resolver.Resolve(context.Background(), givenURL)
 	assert.NoError(t, err)
-	assert.Equal(t, wantResult, gotResult)
+	assert.Equal(t, wantResult, withoutHops(t, gotResult))
 }
 
 // assertErrorsMatch is a helper for comparing two error values, mostly to hide
@@ -602,16 +920,16 @@ func TestResolveTweets(t *testing.T) {
 	t.Parallel()
 
 	okFetcher := &testTweetFetcher{
-		fetch: func(ctx context.Context, tweetURL string) (tweetData, error) {
-			return tweetData{
+		fetch: func(ctx context.Context, tweetURL string) (Tweet, error) {
+			return Tweet{
 				URL:  tweetURL,
 				Text: "tweet text",
 			}, nil
 		},
 	}
 	errFetcher := &testTweetFetcher{
-		fetch: func(ctx context.Context, tweetURL string) (tweetData, error) {
-			return tweetData{}, errors.New("twitter error")
+		fetch: func(ctx context.Context, tweetURL string) (Tweet, error) {
+			return Tweet{}, errors.New("twitter error")
 		},
 	}
 
@@ -632,7 +950,7 @@ func TestResolveTweets(t *testing.T) {
 
 	testCases := map[string]struct {
 		fullTweetURL string
-		tweetFetcher tweetFetcher
+		tweetFetcher TweetFetcher
 		wantErr      error
 		wantResult   Result
 	}{
@@ -643,6 +961,8 @@ func TestResolveTweets(t *testing.T) {
 				ResolvedURL:      "https://twitter.com/username/status/1234", // note that full URL above was trimmed
 				Title:            "tweet text",
 				IntermediateURLs: []string{""}, // will be rendered to match test server URL
+				Outcome:          OutcomeOK,
+				StatusCode:       200,
 			},
 		},
 		"error fetching tweet": {
@@ -654,6 +974,8 @@ func TestResolveTweets(t *testing.T) {
 				ResolvedURL:      "https://twitter.com/username/status/1234", // note that full URL above was trimmed
 				Title:            "",
 				IntermediateURLs: []string{""}, // will be rendered to match test server URL
+				Outcome:          OutcomeError,
+				StatusCode:       200,
 			},
 		},
 	}
@@ -669,8 +991,7 @@ func TestResolveTweets(t *testing.T) {
 			}))
 			defer srv.Close()
 
-			resolver := New(twitterInterceptTransport, 0)
-			resolver.tweetFetcher = tc.tweetFetcher
+			resolver := New(twitterInterceptTransport, 0, WithTweetFetcher(tc.tweetFetcher))
 
 			result, err := resolver.Resolve(context.Background(), srv.URL)
 			assertErrorsMatch(t, tc.wantErr, err)
@@ -680,30 +1001,48 @@ func TestResolveTweets(t *testing.T) {
 				tc.wantResult.IntermediateURLs[idx] = renderURL(srv.URL, hop)
 			}
 
-			assert.Equal(t, tc.wantResult, result)
+			assert.Equal(t, tc.wantResult, withoutHops(t, result))
 		})
 	}
 
 	t.Run("short circuit when given twitter URL as input", func(t *testing.T) {
 		t.Parallel()
 
-		resolver := New(twitterInterceptTransport, 0)
-		resolver.tweetFetcher = okFetcher
+		resolver := New(twitterInterceptTransport, 0, WithTweetFetcher(okFetcher))
 
 		result, err := resolver.Resolve(context.Background(), "https://twitter.com/username/status/1234/photos/1?foo=bar")
 		assert.NoError(t, err)
 		assert.Equal(t, Result{
 			ResolvedURL: "https://twitter.com/username/status/1234", // note that full URL above was trimmed
 			Title:       "tweet text",
-		}, result)
+			Outcome:     OutcomeOK,
+		}, withoutHops(t, result))
+	})
+
+	t.Run("tweet handling disabled", func(t *testing.T) {
+		t.Parallel()
+
+		transport := newHandlerTestTransport(t, "twitter.com", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("<title>page title</title>")) //nolint:errcheck
+		}))
+		resolver := New(transport, 0, WithTweetFetcher(nil))
+
+		result, err := resolver.Resolve(context.Background(), "https://twitter.com/Username/status/1234?foo=bar")
+		assert.NoError(t, err)
+		assert.Equal(t, Result{
+			ResolvedURL: "https://twitter.com/username/status/1234",
+			Title:       "page title",
+			Outcome:     OutcomeOK,
+			StatusCode:  200,
+		}, withoutHops(t, result))
 	})
 }
 
 type testTweetFetcher struct {
-	fetch func(context.Context, string) (tweetData, error)
+	fetch func(context.Context, string) (Tweet, error)
 }
 
-func (f *testTweetFetcher) Fetch(ctx context.Context, tweetURL string) (tweetData, error) {
+func (f *testTweetFetcher) Fetch(ctx context.Context, tweetURL string) (Tweet, error) {
 	return f.fetch(ctx, tweetURL)
 }
 
@@ -726,6 +1065,24 @@ func newSafeTestTransport(t *testing.T) *testTransport {
 	}
 }
 
+// newHandlerTestTransport returns a transport that serves requests to the
+// given host directly from handler, without making any network requests.
+// Requests to any other host fail the test.
+func newHandlerTestTransport(t testing.TB, host string, handler http.Handler) *testTransport {
+	return &testTransport{
+		roundTrip: func(r *http.Request) (*http.Response, error) {
+			if r.URL.Host != host {
+				t.Fatalf("unexpected request to %q", r.URL)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, r)
+			resp := rec.Result()
+			resp.Request = r
+			return resp, nil
+		},
+	}
+}
+
 // renderURL takes a dynamic httptest.Server URL string src and an "expected"
 // URL dst and ensures that dst is relative to the dynamic server URL.
 func renderURL(src string, dst string) string {
@@ -783,3 +1140,183 @@ func loadTitleTestCases(t *testing.T, maxBodySize int) map[string]titleTestCase
 	}
 	return testCases
 }
+
+// withoutHops checks that a result's Hops match its IntermediateURLs, and
+// then removes them so that the rest of the result, which is deterministic,
+// may be compared directly.
+func withoutHops(t *testing.T, result Result) Result {
+	t.Helper()
+	if assert.Equal(t, len(result.IntermediateURLs), len(result.Hops), "hops should match intermediate URLs") {
+		for i, hop := range result.Hops {
+			assert.Equal(t, result.IntermediateURLs[i], hop.URL)
+		}
+	}
+	result.Hops = nil
+	return result
+}
+
+func TestResolveWithCanonicalizer(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/Target?keep=1&drop=2&utm_source=3", http.StatusFound)
+			return
+		}
+		w.Write([]byte("<title>title</title>")) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	canonicalizer, err := canonical.New(
+		canonical.WithParamRules(canonical.ParamRules{{ExcludeParams: []string{"drop"}}}),
+		canonical.WithLowercaseDomains("127.0.0.1"),
+	)
+	assert.NoError(t, err)
+
+	resolver := New(newSafeTestTransport(t), 0, WithCanonicalizer(canonicalizer))
+	result, err := resolver.Resolve(context.Background(), srv.URL+"/redirect")
+	assert.NoError(t, err)
+	assert.Equal(t, srv.URL+"/target?keep=1&utm_source=3", result.ResolvedURL)
+}
+
+func TestUnsupportedScheme(t *testing.T) {
+	t.Parallel()
+
+	t.Run("given url", func(t *testing.T) {
+		t.Parallel()
+
+		resolver := New(newSafeTestTransport(t), 0)
+		result, err := resolver.Resolve(context.Background(), "ftp://example.com/foo")
+		assert.ErrorIs(t, err, ErrUnsupportedScheme)
+		assert.Equal(t, Result{
+			ResolvedURL: "ftp://example.com/foo",
+			Outcome:     OutcomeError,
+		}, result)
+	})
+
+	t.Run("redirect target", func(t *testing.T) {
+		t.Parallel()
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "itms-apps://apps.apple.com/app/id123", http.StatusFound)
+		}))
+		defer srv.Close()
+
+		resolver := New(newSafeTestTransport(t), 0)
+		result, err := resolver.Resolve(context.Background(), srv.URL)
+		assert.ErrorIs(t, err, ErrUnsupportedScheme)
+		assert.Equal(t, Result{
+			ResolvedURL:      "itms-apps://apps.apple.com/app/id123",
+			IntermediateURLs: []string{srv.URL},
+			Outcome:          OutcomeError,
+		}, withoutHops(t, result))
+	})
+}
+
+func BenchmarkResolve(b *testing.B) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect":
+			http.Redirect(w, r, "/final", http.StatusFound)
+		case "/cookie":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "1"})
+			http.Redirect(w, r, "/final", http.StatusFound)
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<title>title</title>")) //nolint:errcheck
+		}
+	})
+
+	for _, path := range []string{"/redirect", "/cookie"} {
+		path := path
+		b.Run(strings.TrimPrefix(path, "/"), func(b *testing.B) {
+			resolver := New(newHandlerTestTransport(b, "example.com", handler), 0)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := resolver.Resolve(context.Background(), "http://example.com"+path); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeBody(t *testing.T) {
+	t.Parallel()
+
+	latin1, _ := charmap.ISO8859_1.NewEncoder().Bytes([]byte("<title>Iñtërnâtiônàlizætiøn</title>"))
+	utf16LE, _ := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder().Bytes([]byte("<title>Iñtërnâtiônàlizætiøn</title>"))
+	utf16BE, _ := unicode.UTF16(unicode.BigEndian, unicode.UseBOM).NewEncoder().Bytes([]byte("<title>Iñtërnâtiônàlizætiøn</title>"))
+
+	testCases := map[string]struct {
+		body        []byte
+		contentType string
+		want        string
+	}{
+		"declared utf-8": {
+			body:        []byte("<title>Iñtërnâtiônàlizætiøn</title>"),
+			contentType: "text/html; charset=UTF-8",
+			want:        "<title>Iñtërnâtiônàlizætiøn</title>",
+		},
+		"declared utf8 with quotes": {
+			body:        []byte("<title>Iñtërnâtiônàlizætiøn</title>"),
+			contentType: `text/html; foo=bar; charset="utf8"`,
+			want:        "<title>Iñtërnâtiônàlizætiøn</title>",
+		},
+		"utf-8 byte order mark": {
+			body:        append([]byte("\xEF\xBB\xBF"), "<title>Iñtërnâtiônàlizætiøn</title>"...),
+			contentType: "text/html; charset=iso-8859-1",
+			want:        "<title>Iñtërnâtiônàlizætiøn</title>",
+		},
+		"utf-16le byte order mark": {
+			body:        utf16LE,
+			contentType: "text/html",
+			want:        "<title>Iñtërnâtiônàlizætiøn</title>",
+		},
+		"utf-16le byte order mark trumps declared utf-8": {
+			body:        utf16LE,
+			contentType: "text/html; charset=utf-8",
+			want:        "<title>Iñtërnâtiônàlizætiøn</title>",
+		},
+		"utf-16be byte order mark": {
+			body:        utf16BE,
+			contentType: "text/html; charset=iso-8859-1",
+			want:        "<title>Iñtërnâtiônàlizætiøn</title>",
+		},
+		"declared latin1": {
+			body:        latin1,
+			contentType: "text/html; charset=iso-8859-1",
+			want:        "<title>Iñtërnâtiônàlizætiøn</title>",
+		},
+		"undeclared utf-8": {
+			body:        []byte("<title>Iñtërnâtiônàlizætiøn</title>"),
+			contentType: "text/html",
+			want:        "<title>Iñtërnâtiônàlizætiøn</title>",
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := decodeBody(tc.body, tc.contentType)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, string(got))
+		})
+	}
+}
+
+func BenchmarkDecodeBody(b *testing.B) {
+	body := []byte("<html><head><title>title</title></head><body>" + strings.Repeat("Iñtërnâtiônàlizætiøn ", 20000) + "</body></html>")
+	for _, contentType := range []string{"text/html; charset=utf-8", "text/html"} {
+		contentType := contentType
+		b.Run(contentType, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := decodeBody(body, contentType); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
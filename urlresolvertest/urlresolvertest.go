@@ -0,0 +1,147 @@
+package urlresolvertest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+
+	"github.com/mccutchen/urlresolver"
+)
+
+// ErrUnscriptedURL is returned by a FakeResolver asked to resolve a URL it
+// has no result for.
+var ErrUnscriptedURL = errors.New("urlresolvertest: no result scripted for url")
+
+// FakeResolver is a urlresolver.Interface that returns scripted results for
+// specific URLs, for unit testing code that depends on a resolver. It is
+// safe for concurrent use.
+type FakeResolver struct {
+	mu      sync.Mutex
+	results map[string]fakeResult
+	calls   []string
+}
+
+type fakeResult struct {
+	result urlresolver.Result
+	err    error
+}
+
+var _ urlresolver.Interface = &FakeResolver{} // FakeResolver implements urlresolver.Interface
+
+// NewFakeResolver creates a new FakeResolver with no scripted results.
+func NewFakeResolver() *FakeResolver {
+	return &FakeResolver{
+		results: make(map[string]fakeResult),
+	}
+}
+
+// Set scripts the result and error returned when resolving the given URL,
+// replacing any previously scripted for it.
+func (f *FakeResolver) Set(givenURL string, result urlresolver.Result, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.results[givenURL] = fakeResult{result: result, err: err}
+}
+
+// Resolve returns the result scripted for the given URL, or an error result
+// wrapping ErrUnscriptedURL if there is none. If ctx is already done, its
+// error is returned instead.
+func (f *FakeResolver) Resolve(ctx context.Context, givenURL string) (urlresolver.Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, givenURL)
+
+	if err := ctx.Err(); err != nil {
+		return urlresolver.Result{ResolvedURL: givenURL, Outcome: urlresolver.OutcomeError}, err
+	}
+	scripted, ok := f.results[givenURL]
+	if !ok {
+		return urlresolver.Result{ResolvedURL: givenURL, Outcome: urlresolver.OutcomeError}, ErrUnscriptedURL
+	}
+	return scripted.result, scripted.err
+}
+
+// Calls returns the URLs this resolver has been asked to resolve, in order.
+func (f *FakeResolver) Calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.calls...)
+}
+
+// RecordingTransport is an http.RoundTripper that records every request
+// before passing it on to another transport. It is safe for concurrent use.
+type RecordingTransport struct {
+	transport http.RoundTripper
+
+	mu       sync.Mutex
+	requests []*http.Request
+}
+
+// NewRecordingTransport creates a RecordingTransport that passes requests on
+// to the given transport.
+func NewRecordingTransport(transport http.RoundTripper) *RecordingTransport {
+	return &RecordingTransport{transport: transport}
+}
+
+// RoundTrip records the request and passes it on to the wrapped transport.
+func (t *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	t.requests = append(t.requests, req)
+	t.mu.Unlock()
+	return t.transport.RoundTrip(req)
+}
+
+// Requests returns the requests made via this transport, in order.
+func (t *RecordingTransport) Requests() []*http.Request {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*http.Request(nil), t.requests...)
+}
+
+// URLs returns the URLs of the requests made via this transport, in order.
+func (t *RecordingTransport) URLs() []string {
+	requests := t.Requests()
+	urls := make([]string, 0, len(requests))
+	for _, req := range requests {
+		urls = append(urls, req.URL.String())
+	}
+	return urls
+}
+
+// HandlerTransport returns an http.RoundTripper that serves every request
+// directly from the given handler, regardless of its host, without making
+// any network requests. Passing it to urlresolver.New allows a real Resolver
+// to be tested against fake sites.
+//
+// Note that a Resolver fetches tweets via its own TweetFetcher, which is not
+// affected; see urlresolver.WithTweetFetcher.
+func HandlerTransport(handler http.Handler) http.RoundTripper {
+	return handlerTransport{handler: handler}
+}
+
+type handlerTransport struct {
+	handler http.Handler
+}
+
+func (t handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+	rec := httptest.NewRecorder()
+	t.handler.ServeHTTP(rec, req)
+	resp := rec.Result()
+	resp.Request = req
+	return resp, nil
+}
+
+// RenderURL resolves the URL reference dst against the base URL src, which
+// is useful for building expected results from the relative URLs given to a
+// handler and the dynamic URL of an httptest.Server.
+func RenderURL(src string, dst string) string {
+	srcURL, _ := url.Parse(src)
+	dstURL, _ := url.Parse(dst)
+	return srcURL.ResolveReference(dstURL).String()
+}
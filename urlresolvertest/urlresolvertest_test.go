@@ -0,0 +1,102 @@
+package urlresolvertest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mccutchen/urlresolver"
+)
+
+func TestFakeResolver(t *testing.T) {
+	t.Parallel()
+
+	errFake := errors.New("fake error")
+
+	resolver := NewFakeResolver()
+	resolver.Set("https://example.com/ok", urlresolver.Result{ResolvedURL: "https://example.com/final", Title: "Title"}, nil)
+	resolver.Set("https://example.com/err", urlresolver.Result{ResolvedURL: "https://example.com/err", Outcome: urlresolver.OutcomeError}, errFake)
+
+	result, err := resolver.Resolve(context.Background(), "https://example.com/ok")
+	assert.NoError(t, err)
+	assert.Equal(t, urlresolver.Result{ResolvedURL: "https://example.com/final", Title: "Title"}, result)
+
+	result, err = resolver.Resolve(context.Background(), "https://example.com/err")
+	assert.ErrorIs(t, err, errFake)
+	assert.Equal(t, urlresolver.OutcomeError, result.Outcome)
+
+	result, err = resolver.Resolve(context.Background(), "https://example.com/unknown")
+	assert.ErrorIs(t, err, ErrUnscriptedURL)
+	assert.Equal(t, urlresolver.Result{ResolvedURL: "https://example.com/unknown", Outcome: urlresolver.OutcomeError}, result)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = resolver.Resolve(ctx, "https://example.com/ok")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	results := urlresolver.ResolveAll(context.Background(), resolver, []string{"https://example.com/ok"}, urlresolver.BatchOptions{})
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, "Title", results[0].Result.Title)
+	}
+
+	assert.Equal(t, []string{
+		"https://example.com/ok",
+		"https://example.com/err",
+		"https://example.com/unknown",
+		"https://example.com/ok",
+		"https://example.com/ok",
+	}, resolver.Calls())
+}
+
+func TestTransports(t *testing.T) {
+	t.Parallel()
+
+	transport := NewRecordingTransport(HandlerTransport(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/target", http.StatusFound)
+			return
+		}
+		w.Write([]byte("<title>Target</title>")) //nolint:errcheck
+	})))
+
+	resolver := urlresolver.New(transport, 0)
+	result, err := resolver.Resolve(context.Background(), "https://example.com/redirect")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/target", result.ResolvedURL)
+	assert.Equal(t, "Target", result.Title)
+	assert.Equal(t, []string{"https://example.com/redirect", "https://example.com/target"}, transport.URLs())
+	if requests := transport.Requests(); assert.Len(t, requests, 2) {
+		assert.Equal(t, http.MethodGet, requests[0].Method)
+	}
+}
+
+func TestRenderURL(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		src  string
+		dst  string
+		want string
+	}{
+		"relative path": {
+			src:  "http://127.0.0.1:1234",
+			dst:  "/foo?bar=baz",
+			want: "http://127.0.0.1:1234/foo?bar=baz",
+		},
+		"absolute url": {
+			src:  "http://127.0.0.1:1234",
+			dst:  "https://example.com/foo",
+			want: "https://example.com/foo",
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, RenderURL(tc.src, tc.dst))
+		})
+	}
+}